@@ -0,0 +1,177 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package aka provides the network side and the UE side of 5G-AKA (6.1.3.2, TS 33.501)
+and EPS-AKA (6.1, TS 33.401) as state machines, which run an entire authentication
+exchange with the values computed by the milenage package, including the failure cases
+(MAC failure, synchronisation failure with re-challenge) and the derivation of the
+anchor key (KSEAF or KASME).
+
+The network side acts as the home network and the serving network at once, i.e., the
+AUSF/UDM and SEAF in 5G or the HSS and MME in EPS, and the messages are exchanged
+in the form of Challenge and Response, without any NAS encoding.
+*/
+package aka
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/wmnsk/milenage"
+)
+
+// Method is the authentication method.
+type Method uint8
+
+// Method definitions.
+const (
+	Method5GAKA Method = iota
+	MethodEPSAKA
+)
+
+// String returns the name of the method.
+func (m Method) String() string {
+	switch m {
+	case Method5GAKA:
+		return "5G-AKA"
+	case MethodEPSAKA:
+		return "EPS-AKA"
+	default:
+		return fmt.Sprintf("Method(%d)", uint8(m))
+	}
+}
+
+// State is the state of Network and UE.
+type State uint8
+
+// State definitions.
+const (
+	// StateIdle is the initial state, in which no challenge is ongoing.
+	StateIdle State = iota
+	// StateChallengeSent is the state of Network waiting for Response.
+	StateChallengeSent
+	// StateResynchronizing is the state of UE which sent the synchronisation failure
+	// and is waiting for the new challenge.
+	StateResynchronizing
+	// StateAuthenticated is the state after the successful authentication, in which
+	// the anchor key is available.
+	StateAuthenticated
+	// StateFailed is the state after the authentication failure.
+	StateFailed
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "Idle"
+	case StateChallengeSent:
+		return "ChallengeSent"
+	case StateResynchronizing:
+		return "Resynchronizing"
+	case StateAuthenticated:
+		return "Authenticated"
+	case StateFailed:
+		return "Failed"
+	default:
+		return fmt.Sprintf("State(%d)", uint8(s))
+	}
+}
+
+// Cause definitions, which are the values of 5GMM cause (9.11.3.2, TS 24.501) and
+// EMM cause (9.9.3.9, TS 24.301) sent in the authentication failure.
+const (
+	CauseMACFailure   uint8 = 20
+	CauseSynchFailure uint8 = 21
+	// CauseNonAuthUnacceptable is "Non-5G authentication unacceptable" in 5G and
+	// "Non-EPS authentication unacceptable" in EPS, which is sent if the AMF separation
+	// bit is not set.
+	CauseNonAuthUnacceptable uint8 = 26
+)
+
+// Error definitions.
+var (
+	ErrUnexpectedState        = errors.New("unexpected state")
+	ErrAuthenticationRejected = errors.New("authentication rejected due to RES/RES* mismatch")
+	ErrMACFailure             = errors.New("MAC failure reported by UE")
+	ErrAuthUnacceptable       = errors.New("authentication unacceptable reported by UE")
+	ErrSynchFailure           = errors.New("MAC-S verification failed in AUTS")
+)
+
+// ServingNetwork is the serving network in which the authentication is run.
+type ServingNetwork struct {
+	PLMN milenage.PLMN
+	// NID is the NID of SNPN, which is used only in 5G-AKA.
+	NID string
+}
+
+// Name returns the serving network name used in 5G-AKA. An error is returned if
+// PLMN or NID is invalid.
+func (s ServingNetwork) Name() (string, error) {
+	if s.NID != "" {
+		return s.PLMN.ServingNetworkNameWithNID(s.NID)
+	}
+	if err := s.PLMN.Validate(); err != nil {
+		return "", err
+	}
+	return s.PLMN.ServingNetworkName(), nil
+}
+
+// Challenge is the challenge sent from Network to UE, which is the content of
+// AUTHENTICATION REQUEST in NAS.
+type Challenge struct {
+	RAND []byte
+	AUTN []byte
+}
+
+// Response is the response sent from UE to Network, which is the content of
+// AUTHENTICATION RESPONSE if Cause is zero, and AUTHENTICATION FAILURE otherwise.
+type Response struct {
+	// RES is RES* in 5G-AKA and RES in EPS-AKA.
+	RES []byte
+	// Cause is the cause of the failure, which is zero on success.
+	Cause uint8
+	// AUTS is set only with CauseSynchFailure.
+	AUTS []byte
+}
+
+// separationBit reports whether the AMF separation bit (bit 0 of AMF) is set, which is
+// required in 5G and EPS (Annex H, TS 33.102).
+func separationBit(amf []byte) bool {
+	return amf[0]&0x80 != 0
+}
+
+// anchorKey computes the response to the challenge and the anchor key, which are the
+// same in Network and UE. m should have RAND, SQN, AMF and the results of F2345.
+func anchorKey(method Method, sn ServingNetwork, m *milenage.Milenage) (res, key []byte, err error) {
+	switch method {
+	case Method5GAKA:
+		snn, err := sn.Name()
+		if err != nil {
+			return nil, nil, err
+		}
+		res, err = m.ComputeRESStarWithSNN(snn)
+		if err != nil {
+			return nil, nil, err
+		}
+		kausf, err := m.ComputeKAUSFWithSNN(snn)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err = milenage.ComputeKSEAFWithSNN(kausf, snn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res, key, nil
+	case MethodEPSAKA:
+		key, err = m.ComputeKASME(sn.PLMN.MCC, sn.PLMN.MNC)
+		if err != nil {
+			return nil, nil, err
+		}
+		return append([]byte(nil), m.RES...), key, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported method: %s", method)
+	}
+}
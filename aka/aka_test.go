@@ -0,0 +1,234 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package aka_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/aka"
+)
+
+var (
+	k   = []byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc}
+	opc = []byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf}
+	sn  = aka.ServingNetwork{PLMN: milenage.PLMN{MCC: "001", MNC: "01"}}
+)
+
+// expectedKey computes the anchor key in the way the other packages do.
+func expectedKey(t *testing.T, method aka.Method, c *aka.Challenge, sqn uint64) []byte {
+	t.Helper()
+
+	m := milenage.NewWithOPc(k, opc, c.RAND, sqn, 0x8000)
+	if _, _, _, _, err := m.F2345(); err != nil {
+		t.Fatal(err)
+	}
+
+	if method == aka.MethodEPSAKA {
+		kasme, err := m.ComputeKASME("001", "01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return kasme
+	}
+
+	kausf, err := m.ComputeKAUSF("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kseaf, err := milenage.ComputeKSEAF(kausf, "001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kseaf
+}
+
+func TestAuthentication(t *testing.T) {
+	for _, method := range []aka.Method{aka.Method5GAKA, aka.MethodEPSAKA} {
+		n := aka.NewNetwork(method, sn, k, opc, 0x10, 0x8000)
+		ue := aka.NewUE(method, sn, k, opc, 0x10)
+
+		c, err := n.Challenge()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := n.Challenge(); !errors.Is(err, aka.ErrUnexpectedState) {
+			t.Errorf("%s: challenge while waiting for response is expected to fail: %v", method, err)
+		}
+
+		r, err := ue.HandleChallenge(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Cause != 0 || (method == aka.Method5GAKA && len(r.RES) != 16) || (method == aka.MethodEPSAKA && len(r.RES) != 8) {
+			t.Errorf("%s: unexpected response: %+v", method, r)
+		}
+
+		next, err := n.HandleResponse(r)
+		if err != nil || next != nil {
+			t.Fatalf("%s: unexpected result: %v, %+v", method, err, next)
+		}
+		if n.State() != aka.StateAuthenticated || ue.State() != aka.StateAuthenticated {
+			t.Errorf("%s: unexpected state: %s, %s", method, n.State(), ue.State())
+		}
+		if n.SQN() != 0x11 || ue.SQN() != 0x11 {
+			t.Errorf("%s: unexpected SQN: %x, %x", method, n.SQN(), ue.SQN())
+		}
+
+		want := expectedKey(t, method, c, 0x11)
+		if diff := cmp.Diff(n.AnchorKey(), want); diff != "" {
+			t.Errorf("%s failed: \n%s", method.String()+"/Network", diff)
+		}
+		if diff := cmp.Diff(ue.AnchorKey(), want); diff != "" {
+			t.Errorf("%s failed: \n%s", method.String()+"/UE", diff)
+		}
+
+		// the same challenge is rejected as SQN is not fresh anymore.
+		r, err = ue.HandleChallenge(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Cause != aka.CauseSynchFailure || ue.AnchorKey() != nil {
+			t.Errorf("%s: replayed challenge is expected to fail: %+v", method, r)
+		}
+	}
+}
+
+func TestResynchronization(t *testing.T) {
+	for _, method := range []aka.Method{aka.Method5GAKA, aka.MethodEPSAKA} {
+		n := aka.NewNetwork(method, sn, k, opc, 0x10, 0x8000)
+		ue := aka.NewUE(method, sn, k, opc, 0x1000)
+
+		c, err := n.Challenge()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := ue.HandleChallenge(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Cause != aka.CauseSynchFailure || len(r.AUTS) != 14 || ue.State() != aka.StateResynchronizing {
+			t.Fatalf("%s: unexpected response: %+v, %s", method, r, ue.State())
+		}
+
+		c, err = n.HandleResponse(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c == nil || n.State() != aka.StateChallengeSent || n.SQN() != 0x1001 {
+			t.Fatalf("%s: unexpected re-challenge: %+v, %s, %x", method, c, n.State(), n.SQN())
+		}
+
+		r, err = ue.HandleChallenge(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := n.HandleResponse(r); err != nil {
+			t.Fatal(err)
+		}
+		if n.AnchorKey() == nil || !cmp.Equal(n.AnchorKey(), ue.AnchorKey()) {
+			t.Errorf("%s: anchor keys mismatch: %x, %x", method, n.AnchorKey(), ue.AnchorKey())
+		}
+	}
+}
+
+func TestFailure(t *testing.T) {
+	otherK := append([]byte(nil), k...)
+	otherK[0] ^= 1
+
+	for _, c := range []struct {
+		description string
+		network     *aka.Network
+		ue          *aka.UE
+		tamper      func(r *aka.Response)
+		cause       uint8
+		err         error
+	}{
+		{
+			"MAC failure",
+			aka.NewNetwork(aka.Method5GAKA, sn, k, opc, 0x10, 0x8000),
+			aka.NewUE(aka.Method5GAKA, sn, otherK, opc, 0x10),
+			nil, aka.CauseMACFailure, aka.ErrMACFailure,
+		}, {
+			"RES* mismatch",
+			aka.NewNetwork(aka.Method5GAKA, sn, k, opc, 0x10, 0x8000),
+			aka.NewUE(aka.Method5GAKA, sn, k, opc, 0x10),
+			func(r *aka.Response) { r.RES[0] ^= 1 }, 0, aka.ErrAuthenticationRejected,
+		}, {
+			"SNN mismatch",
+			aka.NewNetwork(aka.Method5GAKA, aka.ServingNetwork{PLMN: sn.PLMN, NID: "00000000001"}, k, opc, 0x10, 0x8000),
+			aka.NewUE(aka.Method5GAKA, sn, k, opc, 0x10),
+			nil, 0, aka.ErrAuthenticationRejected,
+		}, {
+			"RES mismatch",
+			aka.NewNetwork(aka.MethodEPSAKA, sn, k, opc, 0x10, 0x8000),
+			aka.NewUE(aka.MethodEPSAKA, sn, k, opc, 0x10),
+			func(r *aka.Response) { r.RES[0] ^= 1 }, 0, aka.ErrAuthenticationRejected,
+		}, {
+			"AMF separation bit",
+			aka.NewNetwork(aka.MethodEPSAKA, sn, k, opc, 0x10, 0x0000),
+			aka.NewUE(aka.MethodEPSAKA, sn, k, opc, 0x10),
+			nil, aka.CauseNonAuthUnacceptable, aka.ErrAuthUnacceptable,
+		}, {
+			"invalid AUTS",
+			aka.NewNetwork(aka.Method5GAKA, sn, k, opc, 0x10, 0x8000),
+			aka.NewUE(aka.Method5GAKA, sn, k, opc, 0x1000),
+			func(r *aka.Response) { r.AUTS[13] ^= 1 }, aka.CauseSynchFailure, aka.ErrSynchFailure,
+		},
+	} {
+		ch, err := c.network.Challenge()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := c.ue.HandleChallenge(ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Cause != c.cause {
+			t.Errorf("%s: unexpected cause: %d", c.description, r.Cause)
+		}
+		if c.tamper != nil {
+			c.tamper(r)
+		}
+
+		if _, err := c.network.HandleResponse(r); !errors.Is(err, c.err) {
+			t.Errorf("%s: unexpected error: %v", c.description, err)
+		}
+		if c.network.State() != aka.StateFailed || c.network.AnchorKey() != nil {
+			t.Errorf("%s: unexpected state: %s", c.description, c.network.State())
+		}
+
+		// a new authentication can be started after the failure.
+		if _, err := c.network.Challenge(); err != nil {
+			t.Errorf("%s: %v", c.description, err)
+		}
+	}
+
+	n := aka.NewNetwork(aka.Method5GAKA, sn, k, opc, 0x10, 0x8000)
+	if _, err := n.HandleResponse(&aka.Response{}); !errors.Is(err, aka.ErrUnexpectedState) {
+		t.Errorf("response without challenge is expected to fail: %v", err)
+	}
+}
+
+func TestServingNetworkName(t *testing.T) {
+	snn, err := aka.ServingNetwork{PLMN: sn.PLMN, NID: "00000000001"}.Name()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snn != "5G:mnc001.mcc001.3gppnetwork.org:00000000001" {
+		t.Errorf("unexpected SNN: %s", snn)
+	}
+
+	for _, s := range []aka.ServingNetwork{
+		{PLMN: sn.PLMN, NID: "0000000001"},
+		{PLMN: milenage.PLMN{MCC: "001", MNC: "1"}},
+	} {
+		if _, err := s.Name(); err == nil {
+			t.Errorf("expected error with %+v", s)
+		}
+	}
+}
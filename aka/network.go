@@ -0,0 +1,134 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package aka
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+
+	"github.com/wmnsk/milenage"
+)
+
+// Network is the network side of the authentication for a subscriber.
+//
+// Network is not safe for concurrent use.
+type Network struct {
+	method Method
+	sn     ServingNetwork
+	k, opc []byte
+	sqn    uint64
+	amf    uint16
+
+	state State
+	m     *milenage.Milenage
+	xres  []byte
+	key   []byte
+}
+
+// NewNetwork creates a new Network for the subscriber with k and opc, which runs method
+// in sn. sqn is the last SQN used for the subscriber, which is incremented in each challenge.
+func NewNetwork(method Method, sn ServingNetwork, k, opc []byte, sqn uint64, amf uint16) *Network {
+	return &Network{method: method, sn: sn, k: k, opc: opc, sqn: sqn, amf: amf}
+}
+
+// State returns the current state of n.
+func (n *Network) State() State {
+	return n.state
+}
+
+// SQN returns the last SQN used in the challenge, or the one retrieved from AUTS.
+func (n *Network) SQN() uint64 {
+	return n.sqn
+}
+
+// AnchorKey returns KSEAF in 5G-AKA or KASME in EPS-AKA, which is available only in
+// StateAuthenticated.
+func (n *Network) AnchorKey() []byte {
+	if n.state != StateAuthenticated {
+		return nil
+	}
+	return n.key
+}
+
+// Challenge generates a new authentication vector with a new RAND and the incremented SQN,
+// and returns the challenge to be sent to UE. This can be called in any state except
+// StateChallengeSent, to start a new authentication.
+func (n *Network) Challenge() (*Challenge, error) {
+	if n.state == StateChallengeSent {
+		return nil, ErrUnexpectedState
+	}
+	return n.challenge()
+}
+
+func (n *Network) challenge() (*Challenge, error) {
+	rnd := make([]byte, 16)
+	if _, err := rand.Read(rnd); err != nil {
+		return nil, err
+	}
+
+	m := milenage.NewWithOPc(n.k, n.opc, rnd, n.sqn+1, n.amf)
+	if _, err := m.F1(); err != nil {
+		return nil, err
+	}
+	if _, _, _, _, err := m.F2345(); err != nil {
+		return nil, err
+	}
+	autn, err := m.GenerateAUTN()
+	if err != nil {
+		return nil, err
+	}
+	xres, key, err := anchorKey(n.method, n.sn, m)
+	if err != nil {
+		return nil, err
+	}
+
+	n.sqn++
+	n.m, n.xres, n.key = m, xres, key
+	n.state = StateChallengeSent
+	return &Challenge{RAND: rnd, AUTN: autn}, nil
+}
+
+// HandleResponse handles the response to the last challenge from UE.
+//
+// On synchronisation failure with the valid AUTS, SQN is set to SQNMS in AUTS and the
+// new challenge to be sent to UE is returned. Otherwise, nil is returned with the
+// authentication completed, and the anchor key is available on success.
+// The authentication failures are returned as errors with the state set to StateFailed.
+func (n *Network) HandleResponse(r *Response) (*Challenge, error) {
+	if n.state != StateChallengeSent {
+		return nil, ErrUnexpectedState
+	}
+
+	switch r.Cause {
+	case 0:
+		if subtle.ConstantTimeCompare(n.xres, r.RES) != 1 {
+			return nil, n.fail(ErrAuthenticationRejected)
+		}
+		n.state = StateAuthenticated
+		return nil, nil
+	case CauseSynchFailure:
+		sqnMS, ok, err := n.m.VerifyAUTS(r.AUTS)
+		if err != nil {
+			return nil, n.fail(err)
+		}
+		if !ok {
+			return nil, n.fail(ErrSynchFailure)
+		}
+		n.sqn = milenage.SQNToUint64([6]byte(sqnMS))
+		return n.challenge()
+	case CauseMACFailure:
+		return nil, n.fail(ErrMACFailure)
+	case CauseNonAuthUnacceptable:
+		return nil, n.fail(ErrAuthUnacceptable)
+	default:
+		return nil, n.fail(ErrAuthenticationRejected)
+	}
+}
+
+func (n *Network) fail(err error) error {
+	n.state = StateFailed
+	n.m, n.xres, n.key = nil, nil, nil
+	return err
+}
@@ -0,0 +1,106 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package aka
+
+import (
+	"fmt"
+
+	"github.com/wmnsk/milenage"
+)
+
+// UE is the UE side (USIM and ME) of the authentication.
+//
+// UE is not safe for concurrent use.
+type UE struct {
+	method Method
+	sn     ServingNetwork
+	k, opc []byte
+	sqnMS  uint64
+
+	state State
+	key   []byte
+}
+
+// NewUE creates a new UE with k and opc, which runs method in sn. sqnMS is the highest
+// SQN accepted by the USIM.
+func NewUE(method Method, sn ServingNetwork, k, opc []byte, sqnMS uint64) *UE {
+	return &UE{method: method, sn: sn, k: k, opc: opc, sqnMS: sqnMS}
+}
+
+// State returns the current state of u.
+func (u *UE) State() State {
+	return u.state
+}
+
+// SQN returns SQNMS, the highest SQN accepted by the USIM.
+func (u *UE) SQN() uint64 {
+	return u.sqnMS
+}
+
+// AnchorKey returns KSEAF in 5G-AKA or KASME in EPS-AKA, which is available only in
+// StateAuthenticated.
+func (u *UE) AnchorKey() []byte {
+	if u.state != StateAuthenticated {
+		return nil
+	}
+	return u.key
+}
+
+// HandleChallenge verifies the challenge from Network as described in 6.3.3, TS 33.102
+// and returns the response to be sent. This can be called in any state, as UE responds
+// to every challenge received.
+//
+// The response has RES (or RES*) on success, and the cause with AUTS on failure. SQN in
+// AUTN is accepted only if it is greater than SQNMS, which is then updated to it.
+func (u *UE) HandleChallenge(c *Challenge) (*Response, error) {
+	if len(c.RAND) != 16 || len(c.AUTN) != 16 {
+		return nil, fmt.Errorf("invalid length of RAND or AUTN: %d, %d", len(c.RAND), len(c.AUTN))
+	}
+	u.key = nil
+
+	m := milenage.NewWithOPc(u.k, u.opc, c.RAND, 0, 0)
+	if _, _, _, _, err := m.F2345(); err != nil {
+		return nil, err
+	}
+	sqn := make([]byte, 6)
+	for i := range sqn {
+		sqn[i] = c.AUTN[i] ^ m.AK[i]
+	}
+	m.SQN, m.AMF = sqn, c.AUTN[6:8]
+
+	ok, err := m.VerifyMACA(c.AUTN[8:])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		u.state = StateFailed
+		return &Response{Cause: CauseMACFailure}, nil
+	}
+
+	if !separationBit(m.AMF) {
+		u.state = StateFailed
+		return &Response{Cause: CauseNonAuthUnacceptable}, nil
+	}
+
+	if milenage.SQNToUint64([6]byte(sqn)) <= u.sqnMS {
+		s := milenage.SQNFromUint64(u.sqnMS)
+		m.SQN = s[:]
+		auts, err := m.GenerateAUTS()
+		if err != nil {
+			return nil, err
+		}
+		u.state = StateResynchronizing
+		return &Response{Cause: CauseSynchFailure, AUTS: auts}, nil
+	}
+
+	res, key, err := anchorKey(u.method, u.sn, m)
+	if err != nil {
+		return nil, err
+	}
+	u.sqnMS = milenage.SQNToUint64([6]byte(sqn))
+	u.key = key
+	u.state = StateAuthenticated
+	return &Response{RES: res}, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package akma provides the key derivation of Authentication and Key Management for
+Applications (TS 33.535), which are KAKMA, A-TID/A-KID and KAF, on top of KAUSF computed
+by the milenage package in 5G primary authentication.
+*/
+package akma
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/wmnsk/milenage"
+)
+
+// FC definitions (Annex A, TS 33.535).
+const (
+	fcKAKMA = 0x80
+	fcATID  = 0x81
+	fcKAF   = 0x82
+)
+
+// KAKMA derives KAKMA from KAUSF and SUPI as KDF(KAUSF, "AKMA", SUPI) (A.2, TS 33.535).
+//
+// AAnF receives SUPI from AUSF in the form "imsi-<IMSI>", while the KDF takes only the
+// IMSI digits; the prefix is removed here, so either form works. The same applies to ATID.
+func KAKMA(kausf []byte, supi string) []byte {
+	return milenage.KDF(kausf, fcKAKMA, []byte("AKMA"), supiParam(supi))
+}
+
+// ATID derives A-TID from KAUSF and SUPI as KDF(KAUSF, "A-TID", SUPI) (A.3, TS 33.535).
+func ATID(kausf []byte, supi string) []byte {
+	return milenage.KDF(kausf, fcATID, []byte("A-TID"), supiParam(supi))
+}
+
+// AKID builds A-KID in NAI format from the Routing Indicator, A-TID and the realm which
+// is the Home Network Identifier (6.1, TS 33.535).
+//
+// The username part is in the form "RID.A-TID" with A-TID encoded in base64.
+func AKID(rid string, atid []byte, realm string) string {
+	return rid + "." + base64.StdEncoding.EncodeToString(atid) + "@" + realm
+}
+
+// ParseAKID retrieves the Routing Indicator, A-TID and the realm from A-KID built by AKID.
+func ParseAKID(akid string) (rid string, atid []byte, realm string, err error) {
+	username, realm, ok := strings.Cut(akid, "@")
+	if ok {
+		rid, username, ok = strings.Cut(username, ".")
+	}
+	if !ok || rid == "" || realm == "" {
+		return "", nil, "", fmt.Errorf("invalid A-KID: %s", akid)
+	}
+	if atid, err = base64.StdEncoding.DecodeString(username); err != nil {
+		return "", nil, "", fmt.Errorf("invalid A-TID in A-KID: %w", err)
+	}
+	return rid, atid, realm, nil
+}
+
+// AFID builds AF_ID as the FQDN of AF || Ua* security protocol identifier (6.1, TS 33.535).
+func AFID(fqdn string, uaStarProtocolID []byte) ([]byte, error) {
+	if len(uaStarProtocolID) != 5 {
+		return nil, fmt.Errorf("length of Ua* security protocol identifier should be %d, got: %d", 5, len(uaStarProtocolID))
+	}
+	return append([]byte(fqdn), uaStarProtocolID...), nil
+}
+
+// KAF derives KAF from KAKMA and AF_ID as KDF(KAKMA, AF_ID) (A.4, TS 33.535).
+func KAF(kakma, afID []byte) []byte {
+	return milenage.KDF(kakma, fcKAF, afID)
+}
+
+// Context is the AKMA context stored in AAnF for a subscriber.
+type Context struct {
+	SUPI  string
+	AKID  string
+	KAKMA []byte
+}
+
+// NewContext derives the AKMA context from KAUSF after a successful primary authentication.
+func NewContext(kausf []byte, supi, rid, realm string) (*Context, error) {
+	if len(kausf) != 32 {
+		return nil, fmt.Errorf("length of KAUSF should be %d, got: %d", 32, len(kausf))
+	}
+	return &Context{
+		SUPI:  supi,
+		AKID:  AKID(rid, ATID(kausf, supi), realm),
+		KAKMA: KAKMA(kausf, supi),
+	}, nil
+}
+
+// KAF derives KAF for the AF identified by afID.
+func (c *Context) KAF(afID []byte) []byte {
+	return KAF(c.KAKMA, afID)
+}
+
+func supiParam(supi string) []byte {
+	return []byte(strings.TrimPrefix(supi, "imsi-"))
+}
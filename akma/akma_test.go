@@ -0,0 +1,94 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package akma_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/akma"
+)
+
+const realm = "5gc.mnc001.mcc001.3gppnetwork.org"
+
+func computeKAUSF(t *testing.T) []byte {
+	t.Helper()
+
+	b := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	m := milenage.New(b, b, b, 1, 0x8000)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+	kausf, err := m.ComputeKAUSF("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kausf
+}
+
+// KAKMA, A-KID and KAF below are self-generated with this package, as TS 33.535 has
+// no test data. Both forms of SUPI must result in the same values.
+func TestContext(t *testing.T) {
+	kausf := computeKAUSF(t)
+
+	for _, supi := range []string{"imsi-001010000000001", "001010000000001"} {
+		c, err := akma.NewContext(kausf, supi, "0000", realm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(c.KAKMA, []byte{
+			0x47, 0x5c, 0x5d, 0x5b, 0x22, 0x58, 0x09, 0x38, 0x4f, 0x48, 0xa1, 0x01, 0x7f, 0x39, 0x78, 0x3f,
+			0xd6, 0x4b, 0x00, 0x0e, 0xe9, 0x85, 0x7a, 0x45, 0x03, 0xf5, 0xb8, 0xad, 0xeb, 0x98, 0xd0, 0x82,
+		}); diff != "" {
+			t.Errorf("%s failed: \n%s", supi, diff)
+		}
+		if c.AKID != "0000.aPbyov3edcslzeW5umZhvb83edMBxvOzOzxWb3H5PZQ=@"+realm {
+			t.Errorf("%s failed: unexpected A-KID: %s", supi, c.AKID)
+		}
+
+		afID, err := akma.AFID("af.example.com", []byte{0x01, 0x00, 0x00, 0x00, 0x02})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(c.KAF(afID), []byte{
+			0xab, 0xc6, 0x28, 0x15, 0xe7, 0x2c, 0x22, 0xc6, 0x20, 0xdd, 0x83, 0xc3, 0x56, 0x33, 0x02, 0x95,
+			0x4a, 0x79, 0x3d, 0xf3, 0x58, 0x42, 0x96, 0x35, 0x40, 0xa9, 0x54, 0x1e, 0x66, 0x8c, 0x37, 0xa5,
+		}); diff != "" {
+			t.Errorf("%s failed: \n%s", supi, diff)
+		}
+	}
+}
+
+func TestParseAKID(t *testing.T) {
+	kausf := computeKAUSF(t)
+	atid := akma.ATID(kausf, "imsi-001010000000001")
+
+	rid, gotATID, gotRealm, err := akma.ParseAKID(akma.AKID("0001", atid, realm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rid != "0001" || gotRealm != realm {
+		t.Errorf("unexpected RID or realm: %s, %s", rid, gotRealm)
+	}
+	if diff := cmp.Diff(gotATID, atid); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, akid := range []string{"0001.AAAA", "AAAA@" + realm, ".AAAA@" + realm, "0001.!!!@" + realm} {
+		if _, _, _, err := akma.ParseAKID(akid); err == nil {
+			t.Errorf("expected error with %s", akid)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	if _, err := akma.NewContext(make([]byte, 16), "imsi-001010000000001", "0000", realm); err == nil {
+		t.Error("expected error with invalid KAUSF")
+	}
+	if _, err := akma.AFID("af.example.com", nil); err == nil {
+		t.Error("expected error with invalid Ua* security protocol identifier")
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package auc
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// record is the representation of Credential in the files.
+type record struct {
+	SUPI      string `json:"supi"`
+	K         string `json:"k"`
+	OPc       string `json:"opc"`
+	AMF       string `json:"amf"`
+	SQN       string `json:"sqn"`
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+// csvHeader is the list of columns in CSV file.
+var csvHeader = []string{"supi", "k", "opc", "amf", "sqn", "algorithm"}
+
+func (r *record) credential() (*Credential, error) {
+	k, err := hex.DecodeString(r.K)
+	if err != nil || len(k) != 16 {
+		return nil, fmt.Errorf("invalid K for %s", r.SUPI)
+	}
+	opc, err := hex.DecodeString(r.OPc)
+	if err != nil || len(opc) != 16 {
+		return nil, fmt.Errorf("invalid OPc for %s", r.SUPI)
+	}
+	amf, err := strconv.ParseUint(r.AMF, 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AMF for %s: %w", r.SUPI, err)
+	}
+	sqn, err := strconv.ParseUint(r.SQN, 16, 48)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQN for %s: %w", r.SUPI, err)
+	}
+
+	return &Credential{
+		SUPI:      r.SUPI,
+		K:         k,
+		OPc:       opc,
+		AMF:       uint16(amf),
+		SQN:       sqn,
+		Algorithm: Algorithm(r.Algorithm),
+	}, nil
+}
+
+func newRecord(c *Credential) *record {
+	return &record{
+		SUPI:      c.SUPI,
+		K:         hex.EncodeToString(c.K),
+		OPc:       hex.EncodeToString(c.OPc),
+		AMF:       fmt.Sprintf("%04x", c.AMF),
+		SQN:       fmt.Sprintf("%012x", c.SQN),
+		Algorithm: string(c.Algorithm),
+	}
+}
+
+// FileStore is a CredentialStore backed by a JSON or CSV file.
+//
+// The whole file is loaded into the memory on OpenFileStore, and the file is
+// rewritten every time SQN is updated.
+//
+// The JSON file should be an array of objects with "supi", "k", "opc", "amf", "sqn"
+// and "algorithm" (optional) keys, and the CSV file should have the header line with
+// the same names. K, OPc, AMF and SQN are in hex string.
+type FileStore struct {
+	mu    sync.Mutex // serializes updates to the file.
+	mem   *MemoryStore
+	path  string
+	isCSV bool
+}
+
+// OpenFileStore loads the credentials from the file at path. The format is determined
+// by the extension, which should be either ".json" or ".csv".
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+	case ".csv":
+		s.isCSV = true
+	default:
+		return nil, fmt.Errorf("unsupported file extension: %s", ext)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recs []*record
+	if s.isCSV {
+		recs, err = readCSV(f)
+	} else {
+		err = json.NewDecoder(f).Decode(&recs)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	s.mem = NewMemoryStore()
+	for _, r := range recs {
+		c, err := r.credential()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		s.mem.Add(c)
+	}
+	return s, nil
+}
+
+// Lookup returns the copy of the credential of the subscriber.
+func (s *FileStore) Lookup(ctx context.Context, id string) (*Credential, error) {
+	return s.mem.Lookup(ctx, id)
+}
+
+// IncrementSQN increments SQN of the subscriber atomically, writes it to the file
+// and returns the new SQN. If writing fails, SQN in memory is rolled back so that
+// it does not get ahead of the file.
+func (s *FileStore) IncrementSQN(ctx context.Context, id string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, err := s.mem.Lookup(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	sqn, err := s.mem.IncrementSQN(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.save(); err != nil {
+		return 0, s.rollback(ctx, id, prev.SQN, err)
+	}
+	return sqn, nil
+}
+
+// SetSQN sets SQN of the subscriber and writes it to the file. If writing fails,
+// SQN in memory is rolled back.
+func (s *FileStore) SetSQN(ctx context.Context, id string, sqn uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, err := s.mem.Lookup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.mem.SetSQN(ctx, id, sqn); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		return s.rollback(ctx, id, prev.SQN, err)
+	}
+	return nil
+}
+
+// rollback restores SQN in memory to prev after saveErr.
+func (s *FileStore) rollback(ctx context.Context, id string, prev uint64, saveErr error) error {
+	if err := s.mem.SetSQN(ctx, id, prev); err != nil {
+		return errors.Join(saveErr, err)
+	}
+	return saveErr
+}
+
+// save writes all the credentials to a temporary file and renames it to the path,
+// so that the file is not left half-written. Both the file and the directory are
+// synced so that SQN issued is not rolled back by a crash.
+func (s *FileStore) save() error {
+	creds := s.mem.all()
+	sort.Slice(creds, func(i, j int) bool { return creds[i].SUPI < creds[j].SUPI })
+
+	recs := make([]*record, len(creds))
+	for i, c := range creds {
+		recs[i] = newRecord(c)
+	}
+
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp creates the file with 0600, which should not replace the permissions
+	// of the original file on rename.
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if s.isCSV {
+		err = writeCSV(tmp, recs)
+	} else {
+		enc := json.NewEncoder(tmp)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(recs)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(s.path))
+}
+
+// syncDir syncs the directory to make the rename durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func readCSV(r io.Reader) ([]*record, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	idx := map[string]int{}
+	for i, name := range rows[0] {
+		idx[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvHeader[:5] {
+		if _, ok := idx[name]; !ok {
+			return nil, fmt.Errorf("column %s not found", name)
+		}
+	}
+
+	get := func(row []string, name string) string {
+		if i, ok := idx[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	recs := make([]*record, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		recs = append(recs, &record{
+			SUPI:      get(row, "supi"),
+			K:         get(row, "k"),
+			OPc:       get(row, "opc"),
+			AMF:       get(row, "amf"),
+			SQN:       get(row, "sqn"),
+			Algorithm: get(row, "algorithm"),
+		})
+	}
+	return recs, nil
+}
+
+func writeCSV(w io.Writer, recs []*record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range recs {
+		if err := cw.Write([]string{r.SUPI, r.K, r.OPc, r.AMF, r.SQN, r.Algorithm}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
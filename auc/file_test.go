@@ -0,0 +1,124 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package auc_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage/auc"
+)
+
+func TestFileStore(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		contents string
+	}{
+		{
+			"subscribers.json",
+			`[{"supi": "imsi-001010000000001", "k": "465b5ce8b199b49faa5f0a2ee238a6bc", "opc": "cd63cb71954a9f4e48a5994e37a02baf", "amf": "b9b9", "sqn": "ff9bb4d0b606", "algorithm": "milenage"}]`,
+		}, {
+			"subscribers.csv",
+			"supi,k,opc,amf,sqn,algorithm\nimsi-001010000000001,465b5ce8b199b49faa5f0a2ee238a6bc,cd63cb71954a9f4e48a5994e37a02baf,b9b9,ff9bb4d0b606,milenage\n",
+		},
+	} {
+		ctx := context.Background()
+		path := filepath.Join(t.TempDir(), c.name)
+		if err := os.WriteFile(path, []byte(c.contents), 0o640); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := auc.OpenFileStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Lookup(ctx, "001010000000001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, testCredential()); diff != "" {
+			t.Errorf("%s: \n%s", c.name, diff)
+		}
+
+		if _, err := s.IncrementSQN(ctx, "001010000000001"); err != nil {
+			t.Fatal(err)
+		}
+
+		// the updated SQN should be persisted, with the permissions of the file kept.
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0o640 {
+			t.Errorf("%s: unexpected permissions: %o", c.name, perm)
+		}
+		s, err = auc.OpenFileStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = s.Lookup(ctx, "imsi-001010000000001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.SQN != 0xff9bb4d0b607 {
+			t.Errorf("%s: unexpected SQN: %x", c.name, got.SQN)
+		}
+	}
+}
+
+func TestOpenFileStoreError(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"unknown.txt": "",
+		"bad-k.json":  `[{"supi": "imsi-001010000000001", "k": "00", "opc": "cd63cb71954a9f4e48a5994e37a02baf", "amf": "b9b9", "sqn": "0"}]`,
+		"no-sqn.csv":  "supi,k,opc,amf\nimsi-001010000000001,465b5ce8b199b49faa5f0a2ee238a6bc,cd63cb71954a9f4e48a5994e37a02baf,b9b9\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := auc.OpenFileStore(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFileStoreSaveError(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "store")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "subscribers.csv")
+	contents := "supi,k,opc,amf,sqn\nimsi-001010000000001,465b5ce8b199b49faa5f0a2ee238a6bc,cd63cb71954a9f4e48a5994e37a02baf,b9b9,ff9bb4d0b606\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := auc.OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// writing fails as the directory is gone, and SQN in memory should not be advanced.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if sqn, err := s.IncrementSQN(ctx, "imsi-001010000000001"); err == nil || sqn != 0 {
+		t.Errorf("expected error: %x, %v", sqn, err)
+	}
+	if err := s.SetSQN(ctx, "imsi-001010000000001", 0x10); err == nil {
+		t.Error("expected error")
+	}
+	got, err := s.Lookup(ctx, "imsi-001010000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SQN != 0xff9bb4d0b606 {
+		t.Errorf("unexpected SQN: %x", got.SQN)
+	}
+}
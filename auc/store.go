@@ -0,0 +1,164 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package auc provides the subscriber credential store, which is the core of a minimal
+Authentication Centre (AuC) built on top of the milenage package.
+*/
+package auc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/wmnsk/milenage"
+)
+
+// ErrNotFound is returned when the subscriber is not found in the store.
+var ErrNotFound = errors.New("subscriber not found")
+
+// maxSQN is the maximum value of 48-bit SQN.
+const maxSQN = 1<<48 - 1
+
+// Algorithm is the name of the authentication algorithm used by the subscriber.
+type Algorithm string
+
+// Algorithm definitions.
+const (
+	AlgorithmMilenage Algorithm = "milenage"
+)
+
+// Credential is a set of the authentication credentials of a subscriber.
+type Credential struct {
+	// SUPI is the identifier of the subscriber, either in "imsi-<IMSI>" form or IMSI only.
+	SUPI string
+	// K is a 128-bit subscriber key.
+	K []byte
+	// OPc is a 128-bit value derived from OP and K.
+	OPc []byte
+	// AMF is a 16-bit authentication management field.
+	AMF uint16
+	// SQN is the 48-bit sequence number lastly used.
+	SQN uint64
+	// Algorithm is the authentication algorithm. AlgorithmMilenage is assumed if empty.
+	Algorithm Algorithm
+}
+
+// Milenage returns a new Milenage initialized with the values in c and rand.
+func (c *Credential) Milenage(rand []byte) (*milenage.Milenage, error) {
+	if c.Algorithm != "" && c.Algorithm != AlgorithmMilenage {
+		return nil, fmt.Errorf("unsupported algorithm: %s", c.Algorithm)
+	}
+	return milenage.NewWithOPc(c.K, c.OPc, rand, c.SQN, c.AMF), nil
+}
+
+func (c *Credential) clone() *Credential {
+	cc := *c
+	cc.K = append([]byte(nil), c.K...)
+	cc.OPc = append([]byte(nil), c.OPc...)
+	return &cc
+}
+
+// CredentialStore is the interface to look up and update the subscriber credentials.
+//
+// The subscribers can be identified either by SUPI ("imsi-<IMSI>") or IMSI.
+// The implementations should be safe for concurrent use.
+type CredentialStore interface {
+	// Lookup returns the credential of the subscriber.
+	// ErrNotFound is returned if the subscriber does not exist.
+	Lookup(ctx context.Context, id string) (*Credential, error)
+	// IncrementSQN increments SQN of the subscriber atomically, and returns the new SQN
+	// to be used for the next authentication vector. SQN wraps around at 48 bits.
+	IncrementSQN(ctx context.Context, id string) (uint64, error)
+	// SetSQN sets SQN of the subscriber, e.g., to re-synchronise with SQNMS in AUTS.
+	SetSQN(ctx context.Context, id string, sqn uint64) error
+}
+
+// normalizeID returns the IMSI if id is IMSI-type SUPI, or id as it is otherwise.
+func normalizeID(id string) string {
+	if imsi, ok := strings.CutPrefix(id, "imsi-"); ok {
+		return imsi
+	}
+	return id
+}
+
+// MemoryStore is an in-memory CredentialStore.
+type MemoryStore struct {
+	mu    sync.Mutex
+	creds map[string]*Credential
+}
+
+// NewMemoryStore creates a new MemoryStore with the credentials given.
+func NewMemoryStore(creds ...*Credential) *MemoryStore {
+	s := &MemoryStore{creds: make(map[string]*Credential, len(creds))}
+	for _, c := range creds {
+		s.Add(c)
+	}
+	return s
+}
+
+// Add adds a credential to s, or replaces the one with the same SUPI.
+func (s *MemoryStore) Add(c *Credential) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.creds[normalizeID(c.SUPI)] = c.clone()
+}
+
+// Lookup returns the copy of the credential of the subscriber.
+func (s *MemoryStore) Lookup(_ context.Context, id string) (*Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.creds[normalizeID(id)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return c.clone(), nil
+}
+
+// IncrementSQN increments SQN of the subscriber atomically and returns the new SQN.
+func (s *MemoryStore) IncrementSQN(_ context.Context, id string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.creds[normalizeID(id)]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	c.SQN = (c.SQN + 1) & maxSQN
+	return c.SQN, nil
+}
+
+// SetSQN sets SQN of the subscriber.
+func (s *MemoryStore) SetSQN(_ context.Context, id string, sqn uint64) error {
+	if sqn > maxSQN {
+		return fmt.Errorf("SQN should be in 48 bits, got: %x", sqn)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.creds[normalizeID(id)]
+	if !ok {
+		return ErrNotFound
+	}
+	c.SQN = sqn
+	return nil
+}
+
+// all returns the copies of all the credentials in s.
+func (s *MemoryStore) all() []*Credential {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	creds := make([]*Credential, 0, len(s.creds))
+	for _, c := range s.creds {
+		creds = append(creds, c.clone())
+	}
+	return creds
+}
@@ -0,0 +1,111 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package auc_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage/auc"
+)
+
+var (
+	_ auc.CredentialStore = (*auc.MemoryStore)(nil)
+	_ auc.CredentialStore = (*auc.FileStore)(nil)
+)
+
+func testCredential() *auc.Credential {
+	k := []byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc}
+	opc := []byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf}
+	return &auc.Credential{
+		SUPI:      "imsi-001010000000001",
+		K:         k,
+		OPc:       opc,
+		AMF:       0xb9b9,
+		SQN:       0xff9bb4d0b606,
+		Algorithm: auc.AlgorithmMilenage,
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	want := testCredential()
+	s := auc.NewMemoryStore(want)
+
+	for _, id := range []string{"imsi-001010000000001", "001010000000001"} {
+		got, err := s.Lookup(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("%s: \n%s", id, diff)
+		}
+	}
+
+	if _, err := s.Lookup(ctx, "imsi-001010000000002"); !errors.Is(err, auc.ErrNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	sqn, err := s.IncrementSQN(ctx, "001010000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqn != 0xff9bb4d0b607 {
+		t.Errorf("unexpected SQN: %x", sqn)
+	}
+
+	c, err := s.Lookup(ctx, "001010000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := c.Milenage(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(m.SQN, []byte{0xff, 0x9b, 0xb4, 0xd0, 0xb6, 0x07}); diff != "" {
+		t.Error(diff)
+	}
+
+	// SQN wraps around at 48 bits.
+	if err := s.SetSQN(ctx, "001010000000001", 0xffffffffffff); err != nil {
+		t.Fatal(err)
+	}
+	if sqn, err := s.IncrementSQN(ctx, "001010000000001"); err != nil || sqn != 0 {
+		t.Errorf("unexpected SQN: %x, %v", sqn, err)
+	}
+	if err := s.SetSQN(ctx, "001010000000001", 1<<48); err == nil {
+		t.Error("expected error with SQN out of range")
+	}
+}
+
+func TestMemoryStoreConcurrentIncrement(t *testing.T) {
+	ctx := context.Background()
+	c := testCredential()
+	c.SQN = 0
+	s := auc.NewMemoryStore(c)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.IncrementSQN(ctx, c.SUPI); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.Lookup(ctx, c.SUPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SQN != 100 {
+		t.Errorf("unexpected SQN: %d", got.SQN)
+	}
+}
@@ -0,0 +1,454 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package ausf provides a minimal stand-in of AUSF, which implements the AUSF side of
+Nausf_UEAuthentication service defined in TS 29.509 over HTTP, for both 5G-AKA and EAP-AKA'.
+
+The authentication vectors are retrieved from UDM, which can be the stand-in in the udm package.
+This is meant to be used in the unit tests of AMF, e.g., with net/http/httptest, and is not
+a complete implementation of AUSF.
+*/
+package ausf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/ausf/internal/eapaka"
+	"github.com/wmnsk/milenage/udm"
+)
+
+// Path definitions.
+const (
+	PathUEAuthentications = "/nausf-auth/v1/ue-authentications"
+	Path5GAKAConfirmation = PathUEAuthentications + "/{authCtxId}/5g-aka-confirmation"
+	PathEAPSession        = PathUEAuthentications + "/{authCtxId}/eap-session"
+)
+
+// AuthResult definitions.
+const (
+	AuthResultSuccess = "AUTHENTICATION_SUCCESS"
+	AuthResultFailure = "AUTHENTICATION_FAILURE"
+	AuthResultOngoing = "AUTHENTICATION_ONGOING"
+)
+
+// DefaultContextTTL is the default of Handler.ContextTTL.
+const DefaultContextTTL = 5 * time.Minute
+
+// UDM is the interface to retrieve the authentication vectors from UDM.
+// *udm.Handler implements this.
+type UDM interface {
+	GenerateAuthData(ctx context.Context, supiOrSuci string, req *udm.AuthenticationInfoRequest) (*udm.AuthenticationInfoResult, error)
+}
+
+// AuthenticationInfo is the request body of ue-authentications.
+type AuthenticationInfo struct {
+	SupiOrSuci            string                     `json:"supiOrSuci"`
+	ServingNetworkName    string                     `json:"servingNetworkName"`
+	ResynchronizationInfo *udm.ResynchronizationInfo `json:"resynchronizationInfo,omitempty"`
+}
+
+// UEAuthenticationCtx is the response body of ue-authentications.
+//
+// AuthData5G is either Av5gAka for 5G_AKA or the base64-encoded EAP packet for EAP_AKA_PRIME.
+// Use Av5gAka or EAPPayload to decode it.
+type UEAuthenticationCtx struct {
+	AuthType           string          `json:"authType"`
+	AuthData5G         json.RawMessage `json:"5gAuthData"`
+	Links              map[string]Link `json:"_links"`
+	ServingNetworkName string          `json:"servingNetworkName,omitempty"`
+}
+
+// Av5gAka decodes AuthData5G as Av5gAka.
+func (c *UEAuthenticationCtx) Av5gAka() (*Av5gAka, error) {
+	av := &Av5gAka{}
+	if err := json.Unmarshal(c.AuthData5G, av); err != nil {
+		return nil, err
+	}
+	return av, nil
+}
+
+// EAPPayload decodes AuthData5G as the EAP packet.
+func (c *UEAuthenticationCtx) EAPPayload() ([]byte, error) {
+	var b []byte
+	if err := json.Unmarshal(c.AuthData5G, &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Av5gAka is the 5G SE AV sent to SEAF.
+type Av5gAka struct {
+	RAND      string `json:"rand"`
+	HXRESStar string `json:"hxresStar"`
+	AUTN      string `json:"autn"`
+}
+
+// Link is a link to the resource of the next step.
+type Link struct {
+	Href string `json:"href"`
+}
+
+// ConfirmationData is the request body of 5g-aka-confirmation.
+type ConfirmationData struct {
+	RESStar string `json:"resStar"`
+}
+
+// ConfirmationDataResponse is the response body of 5g-aka-confirmation.
+type ConfirmationDataResponse struct {
+	AuthResult string `json:"authResult"`
+	SUPI       string `json:"supi,omitempty"`
+	KSEAF      string `json:"kseaf,omitempty"`
+}
+
+// EAPSession is the request and response body of eap-session.
+type EAPSession struct {
+	EAPPayload []byte          `json:"eapPayload"`
+	KSEAF      string          `json:"kSeaf,omitempty"`
+	Links      map[string]Link `json:"_links,omitempty"`
+	AuthResult string          `json:"authResult,omitempty"`
+	SUPI       string          `json:"supi,omitempty"`
+}
+
+// authContext is the state of an authentication kept between the requests.
+type authContext struct {
+	supiOrSuci string
+	supi       string
+	snn        string
+	authType   string
+	expiry     time.Time
+
+	// for 5G_AKA.
+	xresStar []byte
+	kseaf    []byte
+
+	// for EAP_AKA_PRIME.
+	rand  []byte
+	xres  []byte
+	keys  *eapaka.Keys
+	eapID uint8
+}
+
+// Handler is the http.Handler that serves Nausf_UEAuthentication with the vectors from UDM.
+type Handler struct {
+	// ContextTTL is how long an authentication context is kept for the next request
+	// from AMF. The contexts expired are removed when a new context is stored.
+	ContextTTL time.Duration
+
+	udm UDM
+	mux *http.ServeMux
+
+	mu   sync.Mutex
+	ctxs map[string]*authContext
+}
+
+// NewHandler creates a new Handler that retrieves the vectors from u.
+func NewHandler(u UDM) *Handler {
+	h := &Handler{ContextTTL: DefaultContextTTL, udm: u, mux: http.NewServeMux(), ctxs: map[string]*authContext{}}
+	h.mux.HandleFunc("POST "+PathUEAuthentications, h.ueAuthentications)
+	h.mux.HandleFunc("PUT "+Path5GAKAConfirmation, h.confirm5GAKA)
+	h.mux.HandleFunc("POST "+PathEAPSession, h.eapSession)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) ueAuthentications(w http.ResponseWriter, r *http.Request) {
+	req := &AuthenticationInfo{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		udm.WriteProblem(w, problem(http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "invalid request body: %v", err))
+		return
+	}
+
+	ac := &authContext{supiOrSuci: req.SupiOrSuci, snn: req.ServingNetworkName}
+	authData, err := h.challenge(r.Context(), ac, req.ResynchronizationInfo)
+	if err != nil {
+		udm.WriteProblem(w, err)
+		return
+	}
+
+	id, err := h.store(ac)
+	if err != nil {
+		udm.WriteProblem(w, err)
+		return
+	}
+
+	loc := baseURL(r) + PathUEAuthentications + "/" + id
+	links := map[string]Link{"5g-aka": {Href: loc + "/5g-aka-confirmation"}}
+	if ac.authType == udm.AuthTypeEAPAKAPrime {
+		links = map[string]Link{"eap-session": {Href: loc + "/eap-session"}}
+	}
+
+	w.Header().Set("Location", loc)
+	writeJSON(w, http.StatusCreated, &UEAuthenticationCtx{
+		AuthType:           ac.authType,
+		AuthData5G:         authData,
+		Links:              links,
+		ServingNetworkName: ac.snn,
+	})
+}
+
+// challenge retrieves a vector from UDM and prepares ac for the confirmation,
+// and returns 5gAuthData to be sent.
+func (h *Handler) challenge(ctx context.Context, ac *authContext, resync *udm.ResynchronizationInfo) (json.RawMessage, error) {
+	if err := milenage.ValidateServingNetworkName(ac.snn); err != nil {
+		return nil, problem(http.StatusBadRequest, "SERVING_NETWORK_NOT_AUTHORIZED", "%v", err)
+	}
+
+	res, err := h.udm.GenerateAuthData(ctx, ac.supiOrSuci, &udm.AuthenticationInfoRequest{
+		ServingNetworkName:    ac.snn,
+		ResynchronizationInfo: resync,
+	})
+	if err != nil {
+		return nil, err
+	}
+	av := res.AuthenticationVector
+	if av == nil {
+		return nil, fmt.Errorf("no authentication vector for %s", ac.supiOrSuci)
+	}
+	ac.supi, ac.authType = res.SUPI, res.AuthType
+
+	rnd, err := decodeHex(av.RAND, av.AUTN)
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.AuthType {
+	case udm.AuthType5GAKA:
+		vals, err := decodeHex(av.XRESStar, av.KAUSF)
+		if err != nil {
+			return nil, err
+		}
+		ac.xresStar = vals[0]
+		ac.kseaf, err = milenage.ComputeKSEAFWithSNN(vals[1], ac.snn)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(&Av5gAka{
+			RAND:      av.RAND,
+			HXRESStar: hex.EncodeToString(milenage.ComputeHXRESStar(rnd[0], ac.xresStar)),
+			AUTN:      av.AUTN,
+		})
+	case udm.AuthTypeEAPAKAPrime:
+		vals, err := decodeHex(av.XRES, av.CKPrime, av.IKPrime)
+		if err != nil {
+			return nil, err
+		}
+		ac.rand, ac.xres = rnd[0], vals[0]
+		// The identity in MK derivation is SUPI, which is IMSI without prefix here (Annex F, TS 33.501).
+		ac.keys = eapaka.DeriveKeys(vals[1], vals[2], strings.TrimPrefix(ac.supi, "imsi-"))
+		ac.eapID++
+
+		// KAUSF is the most significant 256 bits of EMSK (6.1.3.1, TS 33.501).
+		ac.kseaf, err = milenage.ComputeKSEAFWithSNN(ac.keys.EMSK[:32], ac.snn)
+		if err != nil {
+			return nil, err
+		}
+
+		pkt := &eapaka.Packet{
+			Code:       eapaka.CodeRequest,
+			Identifier: ac.eapID,
+			Subtype:    eapaka.SubtypeChallenge,
+			RAND:       rnd[0],
+			AUTN:       rnd[1],
+			KDFInput:   ac.snn,
+			KDF:        eapaka.KDFDefault,
+		}
+		return json.Marshal(pkt.Sign(ac.keys.KAut))
+	default:
+		return nil, fmt.Errorf("unsupported AuthType: %s", res.AuthType)
+	}
+}
+
+func (h *Handler) confirm5GAKA(w http.ResponseWriter, r *http.Request) {
+	req := &ConfirmationData{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		udm.WriteProblem(w, problem(http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "invalid request body: %v", err))
+		return
+	}
+
+	// The context is removed before the verification so that a challenge is confirmed only once.
+	ac, ok := h.loadAndDelete(r.PathValue("authCtxId"), udm.AuthType5GAKA)
+	if !ok {
+		udm.WriteProblem(w, problem(http.StatusNotFound, "CONTEXT_NOT_FOUND", "authentication context not found"))
+		return
+	}
+
+	resStar, err := hex.DecodeString(req.RESStar)
+	if err != nil || subtle.ConstantTimeCompare(resStar, ac.xresStar) != 1 {
+		writeJSON(w, http.StatusOK, &ConfirmationDataResponse{AuthResult: AuthResultFailure})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &ConfirmationDataResponse{
+		AuthResult: AuthResultSuccess,
+		SUPI:       ac.supi,
+		KSEAF:      hex.EncodeToString(ac.kseaf),
+	})
+}
+
+func (h *Handler) eapSession(w http.ResponseWriter, r *http.Request) {
+	req := &EAPSession{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		udm.WriteProblem(w, problem(http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "invalid request body: %v", err))
+		return
+	}
+	pkt, err := eapaka.Parse(req.EAPPayload)
+	if err != nil || pkt.Code != eapaka.CodeResponse {
+		udm.WriteProblem(w, problem(http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "invalid EAP payload: %v", err))
+		return
+	}
+
+	// The context is removed before handling the response so that the concurrent requests
+	// on the same context do not share it, and a challenge is responded only once.
+	id := r.PathValue("authCtxId")
+	ac, ok := h.loadAndDelete(id, udm.AuthTypeEAPAKAPrime)
+	if !ok {
+		udm.WriteProblem(w, problem(http.StatusNotFound, "CONTEXT_NOT_FOUND", "authentication context not found"))
+		return
+	}
+
+	// The UE failed to verify AUTN due to SQN out of range. Get a new vector
+	// re-synchronised with AUTS and send a new challenge in a new context.
+	if pkt.Subtype == eapaka.SubtypeSynchronizationFailure && pkt.AUTS != nil {
+		next := &authContext{supiOrSuci: ac.supiOrSuci, snn: ac.snn, eapID: ac.eapID}
+		payload, err := h.challenge(r.Context(), next, &udm.ResynchronizationInfo{
+			RAND: hex.EncodeToString(ac.rand),
+			AUTS: hex.EncodeToString(pkt.AUTS),
+		})
+		if err != nil {
+			udm.WriteProblem(w, err)
+			return
+		}
+
+		var b []byte
+		if err := json.Unmarshal(payload, &b); err != nil {
+			udm.WriteProblem(w, err)
+			return
+		}
+		h.set(id, next)
+
+		writeJSON(w, http.StatusOK, &EAPSession{
+			EAPPayload: b,
+			Links:      map[string]Link{"eap-session": {Href: baseURL(r) + r.URL.Path}},
+			AuthResult: AuthResultOngoing,
+		})
+		return
+	}
+
+	if pkt.Subtype == eapaka.SubtypeChallenge && ac.verify(req.EAPPayload, pkt) {
+		writeJSON(w, http.StatusOK, &EAPSession{
+			EAPPayload: (&eapaka.Packet{Code: eapaka.CodeSuccess, Identifier: ac.eapID}).Marshal(),
+			KSEAF:      hex.EncodeToString(ac.kseaf),
+			AuthResult: AuthResultSuccess,
+			SUPI:       ac.supi,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &EAPSession{
+		EAPPayload: (&eapaka.Packet{Code: eapaka.CodeFailure, Identifier: ac.eapID}).Marshal(),
+		AuthResult: AuthResultFailure,
+	})
+}
+
+// verify verifies AT_MAC and AT_RES in the EAP-Response/AKA'-Challenge.
+func (ac *authContext) verify(b []byte, pkt *eapaka.Packet) bool {
+	if ok, err := eapaka.VerifyMAC(b, ac.keys.KAut); err != nil || !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(pkt.RES, ac.xres) == 1
+}
+
+func (h *Handler) store(ac *authContext) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	h.set(id, ac)
+	return id, nil
+}
+
+// set stores ac with the expiry, and removes the expired contexts so that the
+// contexts left by the AMFs that never come back do not pile up.
+func (h *Handler) set(id string, ac *authContext) {
+	now := time.Now()
+	ac.expiry = now.Add(h.ContextTTL)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for k, v := range h.ctxs {
+		if now.After(v.expiry) {
+			delete(h.ctxs, k)
+		}
+	}
+	h.ctxs[id] = ac
+}
+
+// loadAndDelete takes the context of authType out of h, which is done under a single
+// lock so that only one of the concurrent requests on the same context gets it.
+func (h *Handler) loadAndDelete(id, authType string) (*authContext, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ac, ok := h.ctxs[id]
+	if !ok || ac.authType != authType {
+		return nil, false
+	}
+	delete(h.ctxs, id)
+	if time.Now().After(ac.expiry) {
+		return nil, false
+	}
+	return ac, true
+}
+
+func problem(status int, cause, format string, a ...any) *udm.ProblemDetails {
+	return &udm.ProblemDetails{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: fmt.Sprintf(format, a...),
+		Cause:  cause,
+	}
+}
+
+func decodeHex(values ...string) ([][]byte, error) {
+	out := make([][]byte, len(values))
+	for i, v := range values {
+		b, err := hex.DecodeString(v)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("invalid value in authentication vector: %q", v)
+		}
+		out[i] = b
+	}
+	return out, nil
+}
+
+func baseURL(r *http.Request) string {
+	if r.TLS != nil {
+		return "https://" + r.Host
+	}
+	return "http://" + r.Host
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
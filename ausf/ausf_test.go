@@ -0,0 +1,440 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package ausf_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/auc"
+	"github.com/wmnsk/milenage/ausf"
+	"github.com/wmnsk/milenage/ausf/internal/eapaka"
+	"github.com/wmnsk/milenage/udm"
+)
+
+const (
+	supi = "imsi-001010000000001"
+	snn  = "5G:mnc001.mcc001.3gppnetwork.org"
+)
+
+var (
+	k   = []byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc}
+	opc = []byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf}
+)
+
+func newServer(t *testing.T, authType string, sqn uint64) *httptest.Server {
+	t.Helper()
+
+	store := auc.NewMemoryStore(&auc.Credential{SUPI: supi, K: k, OPc: opc, AMF: 0x8000, SQN: sqn})
+	u := udm.NewHandler(store)
+	u.AuthType = authType
+
+	srv := httptest.NewServer(ausf.NewHandler(u))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, method, url string, req, v any) int {
+	t.Helper()
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := http.NewRequest(method, url, bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	rsp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	if err := json.NewDecoder(rsp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return rsp.StatusCode
+}
+
+func authenticate(t *testing.T, srv *httptest.Server) *ausf.UEAuthenticationCtx {
+	t.Helper()
+
+	res := &ausf.UEAuthenticationCtx{}
+	req := &ausf.AuthenticationInfo{SupiOrSuci: supi, ServingNetworkName: snn}
+	if status := do(t, http.MethodPost, srv.URL+ausf.PathUEAuthentications, req, res); status != http.StatusCreated {
+		t.Fatalf("unexpected status: %d", status)
+	}
+	return res
+}
+
+// ue computes the response to RAND and AUTN in the way UE does.
+func ue(t *testing.T, rnd, autn []byte) *milenage.Milenage {
+	t.Helper()
+
+	m := milenage.NewWithOPc(k, opc, rnd, 0, 0x8000)
+	if _, _, _, _, err := m.F2345(); err != nil {
+		t.Fatal(err)
+	}
+	sqn := make([]byte, 6)
+	for i := range sqn {
+		sqn[i] = autn[i] ^ m.AK[i]
+	}
+	m.SQN = sqn
+
+	if ok, err := m.VerifyMACA(autn[8:]); err != nil || !ok {
+		t.Fatalf("MAC-A verification failed: %v", err)
+	}
+	return m
+}
+
+func Test5GAKA(t *testing.T) {
+	srv := newServer(t, udm.AuthType5GAKA, 0)
+
+	for _, c := range []struct {
+		description string
+		tamper      bool
+		result      string
+	}{
+		{"success", false, ausf.AuthResultSuccess},
+		{"wrong RES*", true, ausf.AuthResultFailure},
+	} {
+		ctx := authenticate(t, srv)
+		av, err := ctx.Av5gAka()
+		if err != nil {
+			t.Fatal(err)
+		}
+		link, ok := ctx.Links["5g-aka"]
+		if ctx.AuthType != udm.AuthType5GAKA || !ok {
+			t.Fatalf("%s: unexpected context: %+v", c.description, ctx)
+		}
+
+		rnd, err := hex.DecodeString(av.RAND)
+		if err != nil {
+			t.Fatal(err)
+		}
+		autn, err := hex.DecodeString(av.AUTN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := ue(t, rnd, autn)
+		resStar, err := m.ComputeRESStar("001", "01")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// SEAF checks HXRES* before sending RES* to AUSF.
+		if hex.EncodeToString(milenage.ComputeHXRESStar(rnd, resStar)) != av.HXRESStar {
+			t.Errorf("%s: unexpected HXRES*: %s", c.description, av.HXRESStar)
+		}
+
+		if c.tamper {
+			resStar[0] ^= 1
+		}
+		res := &ausf.ConfirmationDataResponse{}
+		req := &ausf.ConfirmationData{RESStar: hex.EncodeToString(resStar)}
+		if status := do(t, http.MethodPut, link.Href, req, res); status != http.StatusOK {
+			t.Fatalf("%s: unexpected status: %d", c.description, status)
+		}
+		if res.AuthResult != c.result {
+			t.Errorf("%s: unexpected result: %+v", c.description, res)
+		}
+		if c.tamper {
+			continue
+		}
+
+		kausf, err := m.ComputeKAUSF("001", "01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		kseaf, err := milenage.ComputeKSEAF(kausf, "001", "01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.SUPI != supi || res.KSEAF != hex.EncodeToString(kseaf) {
+			t.Errorf("%s: unexpected result: %+v", c.description, res)
+		}
+
+		// The context is removed after the confirmation.
+		pd := &udm.ProblemDetails{}
+		if status := do(t, http.MethodPut, link.Href, req, pd); status != http.StatusNotFound {
+			t.Errorf("%s: unexpected status on second confirmation: %d", c.description, status)
+		}
+	}
+}
+
+func Test5GAKAConcurrentConfirmation(t *testing.T) {
+	srv := newServer(t, udm.AuthType5GAKA, 0)
+
+	ctx := authenticate(t, srv)
+	av, err := ctx.Av5gAka()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd, err := hex.DecodeString(av.RAND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	autn, err := hex.DecodeString(av.AUTN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := ue(t, rnd, autn)
+	resStar, err := m.ComputeRESStar("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(&ausf.ConfirmationData{RESStar: hex.EncodeToString(resStar)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only one of the confirmations with the same RES* gets KSEAF.
+	const n = 8
+	statuses := make(chan int, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := http.NewRequest(http.MethodPut, ctx.Links["5g-aka"].Href, bytes.NewReader(b))
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			rsp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			rsp.Body.Close()
+			statuses <- rsp.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	var ok int
+	for status := range statuses {
+		switch status {
+		case http.StatusOK:
+			ok++
+		case http.StatusNotFound:
+		default:
+			t.Errorf("unexpected status: %d", status)
+		}
+	}
+	if ok != 1 {
+		t.Errorf("challenge is confirmed %d times", ok)
+	}
+}
+
+func Test5GAKANSWO(t *testing.T) {
+	srv := newServer(t, udm.AuthType5GAKA, 0)
+
+	ctx := &ausf.UEAuthenticationCtx{}
+	req := &ausf.AuthenticationInfo{SupiOrSuci: supi, ServingNetworkName: milenage.ServingNetworkNameNSWO}
+	if status := do(t, http.MethodPost, srv.URL+ausf.PathUEAuthentications, req, ctx); status != http.StatusCreated {
+		t.Fatalf("unexpected status: %d", status)
+	}
+	av, err := ctx.Av5gAka()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd, err := hex.DecodeString(av.RAND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	autn, err := hex.DecodeString(av.AUTN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := ue(t, rnd, autn)
+	resStar, err := m.ComputeRESStarWithSNN(milenage.ServingNetworkNameNSWO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &ausf.ConfirmationDataResponse{}
+	if status := do(t, http.MethodPut, ctx.Links["5g-aka"].Href, &ausf.ConfirmationData{RESStar: hex.EncodeToString(resStar)}, res); status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", status)
+	}
+	kausf, err := m.ComputeKAUSFWithSNN(milenage.ServingNetworkNameNSWO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kseaf, err := milenage.ComputeKSEAFWithSNN(kausf, milenage.ServingNetworkNameNSWO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.AuthResult != ausf.AuthResultSuccess || res.KSEAF != hex.EncodeToString(kseaf) {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+func TestContextExpiry(t *testing.T) {
+	store := auc.NewMemoryStore(&auc.Credential{SUPI: supi, K: k, OPc: opc, AMF: 0x8000})
+	h := ausf.NewHandler(udm.NewHandler(store))
+	h.ContextTTL = time.Millisecond
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	ctx := authenticate(t, srv)
+	time.Sleep(10 * time.Millisecond)
+
+	pd := &udm.ProblemDetails{}
+	if status := do(t, http.MethodPut, ctx.Links["5g-aka"].Href, &ausf.ConfirmationData{}, pd); status != http.StatusNotFound {
+		t.Errorf("unexpected status with expired context: %d", status)
+	}
+}
+
+func TestEAPAKAPrime(t *testing.T) {
+	srv := newServer(t, udm.AuthTypeEAPAKAPrime, 0)
+
+	ctx := authenticate(t, srv)
+	link, ok := ctx.Links["eap-session"]
+	if ctx.AuthType != udm.AuthTypeEAPAKAPrime || !ok {
+		t.Fatalf("unexpected context: %+v", ctx)
+	}
+	b, err := ctx.EAPPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// UE has SQNMS larger than HE and requests re-synchronisation first.
+	challenge, err := eapaka.Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := milenage.Params{K: [16]byte(k), OPc: [16]byte(opc), RAND: [16]byte(challenge.RAND), SQN: milenage.SQNFromUint64(0x1000)}
+	auts := milenage.ComputeAUTS(p)
+	syncFailure := &eapaka.Packet{
+		Code:       eapaka.CodeResponse,
+		Identifier: challenge.Identifier,
+		Subtype:    eapaka.SubtypeSynchronizationFailure,
+		AUTS:       auts[:],
+		KDF:        eapaka.KDFDefault,
+	}
+	sess := &ausf.EAPSession{}
+	if status := do(t, http.MethodPost, link.Href, &ausf.EAPSession{EAPPayload: syncFailure.Marshal()}, sess); status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", status)
+	}
+	if sess.AuthResult != ausf.AuthResultOngoing {
+		t.Fatalf("unexpected result: %+v", sess)
+	}
+
+	challenge, err = eapaka.Parse(sess.EAPPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge.Subtype != eapaka.SubtypeChallenge || challenge.KDFInput != snn {
+		t.Fatalf("unexpected challenge: %+v", challenge)
+	}
+	m := ue(t, challenge.RAND, challenge.AUTN)
+	if sqn := milenage.SQNToUint64([6]byte(m.SQN)); sqn != 0x1001 {
+		t.Errorf("unexpected SQN: %x", sqn)
+	}
+
+	ckPrime, ikPrime, err := m.ComputeCKIKPrime(snn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := eapaka.DeriveKeys(ckPrime, ikPrime, strings.TrimPrefix(supi, "imsi-"))
+	if ok, err := eapaka.VerifyMAC(sess.EAPPayload, keys.KAut); err != nil || !ok {
+		t.Fatalf("MAC verification failed: %v", err)
+	}
+
+	rsp := (&eapaka.Packet{
+		Code:       eapaka.CodeResponse,
+		Identifier: challenge.Identifier,
+		Subtype:    eapaka.SubtypeChallenge,
+		RES:        m.RES,
+	}).Sign(keys.KAut)
+	sess = &ausf.EAPSession{}
+	if status := do(t, http.MethodPost, link.Href, &ausf.EAPSession{EAPPayload: rsp}, sess); status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", status)
+	}
+
+	kseaf, err := milenage.ComputeKSEAF(keys.EMSK[:32], "001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.AuthResult != ausf.AuthResultSuccess || sess.SUPI != supi || sess.KSEAF != hex.EncodeToString(kseaf) {
+		t.Errorf("unexpected result: %+v", sess)
+	}
+	if pkt, err := eapaka.Parse(sess.EAPPayload); err != nil || pkt.Code != eapaka.CodeSuccess {
+		t.Errorf("unexpected EAP payload: %+v, %v", pkt, err)
+	}
+}
+
+func TestEAPAKAPrimeFailure(t *testing.T) {
+	srv := newServer(t, udm.AuthTypeEAPAKAPrime, 0)
+
+	ctx := authenticate(t, srv)
+	b, err := ctx.EAPPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := eapaka.Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// AT_MAC computed with a wrong key.
+	rsp := (&eapaka.Packet{
+		Code:       eapaka.CodeResponse,
+		Identifier: challenge.Identifier,
+		Subtype:    eapaka.SubtypeChallenge,
+		RES:        make([]byte, 8),
+	}).Sign(make([]byte, 32))
+	sess := &ausf.EAPSession{}
+	if status := do(t, http.MethodPost, ctx.Links["eap-session"].Href, &ausf.EAPSession{EAPPayload: rsp}, sess); status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", status)
+	}
+	if sess.AuthResult != ausf.AuthResultFailure || sess.KSEAF != "" {
+		t.Errorf("unexpected result: %+v", sess)
+	}
+	if pkt, err := eapaka.Parse(sess.EAPPayload); err != nil || pkt.Code != eapaka.CodeFailure {
+		t.Errorf("unexpected EAP payload: %+v, %v", pkt, err)
+	}
+}
+
+func TestUEAuthenticationsError(t *testing.T) {
+	srv := newServer(t, udm.AuthType5GAKA, 0)
+
+	for _, c := range []struct {
+		description string
+		id          string
+		snn         string
+		status      int
+		cause       string
+	}{
+		{"unknown subscriber", "imsi-001010000000002", snn, http.StatusNotFound, "USER_NOT_FOUND"},
+		{"empty SNN", supi, "", http.StatusBadRequest, "SERVING_NETWORK_NOT_AUTHORIZED"},
+	} {
+		pd := &udm.ProblemDetails{}
+		req := &ausf.AuthenticationInfo{SupiOrSuci: c.id, ServingNetworkName: c.snn}
+		status := do(t, http.MethodPost, srv.URL+ausf.PathUEAuthentications, req, pd)
+		if status != c.status || pd.Cause != c.cause {
+			t.Errorf("%s: unexpected response: %d, %+v", c.description, status, pd)
+		}
+	}
+
+	pd := &udm.ProblemDetails{}
+	url := srv.URL + strings.Replace(ausf.Path5GAKAConfirmation, "{authCtxId}", "unknown", 1)
+	if status := do(t, http.MethodPut, url, &ausf.ConfirmationData{}, pd); status != http.StatusNotFound {
+		t.Errorf("unexpected status: %d", status)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package eapaka provides the minimal set of EAP-AKA' (RFC 9048) functionalities,
+which are the key derivation and the encoding/decoding of the EAP packets with the
+attributes used in the challenge, to run EAP-AKA' with the values computed by the
+milenage package.
+
+This is internal to the ausf package, which is the only user of it.
+*/
+package eapaka
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// EAP Code definitions.
+const (
+	CodeRequest  uint8 = 1
+	CodeResponse uint8 = 2
+	CodeSuccess  uint8 = 3
+	CodeFailure  uint8 = 4
+)
+
+// TypeAKAPrime is the EAP method type of EAP-AKA'.
+const TypeAKAPrime uint8 = 50
+
+// Subtype definitions.
+const (
+	SubtypeChallenge              uint8 = 1
+	SubtypeAuthenticationReject   uint8 = 2
+	SubtypeSynchronizationFailure uint8 = 4
+	SubtypeClientError            uint8 = 14
+)
+
+// Attribute type definitions.
+const (
+	AttrRAND     uint8 = 1
+	AttrAUTN     uint8 = 2
+	AttrRES      uint8 = 3
+	AttrAUTS     uint8 = 4
+	AttrMAC      uint8 = 11
+	AttrKDFInput uint8 = 23
+	AttrKDF      uint8 = 24
+)
+
+// KDFDefault is the default KDF value in AT_KDF, which is the one defined in RFC 9048.
+const KDFDefault uint16 = 1
+
+// Keys is the set of keys derived from CK' and IK' (3.3, RFC 9048).
+type Keys struct {
+	KEncr []byte
+	KAut  []byte
+	KRe   []byte
+	MSK   []byte
+	EMSK  []byte
+}
+
+// DeriveKeys derives the keys from CK', IK' and the identity of the peer,
+// with MK = PRF'(IK'|CK', "EAP-AKA'"|Identity).
+func DeriveKeys(ckPrime, ikPrime []byte, identity string) *Keys {
+	key := make([]byte, 0, len(ikPrime)+len(ckPrime))
+	key = append(key, ikPrime...)
+	key = append(key, ckPrime...)
+
+	mk := prf(key, []byte("EAP-AKA'"+identity), 208)
+	return &Keys{
+		KEncr: mk[0:16],
+		KAut:  mk[16:48],
+		KRe:   mk[48:80],
+		MSK:   mk[80:144],
+		EMSK:  mk[144:208],
+	}
+}
+
+// prf is PRF' defined in 3.4.1, RFC 9048.
+func prf(key, s []byte, n int) []byte {
+	out := make([]byte, 0, n+sha256.Size)
+	var t []byte
+	for i := byte(1); len(out) < n; i++ {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(t)
+		mac.Write(s)
+		mac.Write([]byte{i})
+		t = mac.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:n]
+}
+
+// Packet is an EAP packet. The fields other than Code and Identifier are used only
+// for Request and Response of EAP-AKA', and the attributes are omitted if empty.
+type Packet struct {
+	Code       uint8
+	Identifier uint8
+	Subtype    uint8
+
+	RAND     []byte
+	AUTN     []byte
+	RES      []byte
+	AUTS     []byte
+	KDFInput string
+	KDF      uint16
+	MAC      []byte
+}
+
+// Marshal returns the byte sequence of p.
+func (p *Packet) Marshal() []byte {
+	b := []byte{p.Code, p.Identifier, 0, 0}
+	if p.Code == CodeRequest || p.Code == CodeResponse {
+		b = append(b, TypeAKAPrime, p.Subtype, 0, 0)
+		if p.RAND != nil {
+			b = appendAttr(b, AttrRAND, []byte{0, 0}, p.RAND)
+		}
+		if p.AUTN != nil {
+			b = appendAttr(b, AttrAUTN, []byte{0, 0}, p.AUTN)
+		}
+		if p.RES != nil {
+			b = appendAttr(b, AttrRES, binary.BigEndian.AppendUint16(nil, uint16(len(p.RES)*8)), p.RES)
+		}
+		if p.AUTS != nil {
+			b = appendAttr(b, AttrAUTS, nil, p.AUTS)
+		}
+		if p.KDFInput != "" {
+			b = appendAttr(b, AttrKDFInput, binary.BigEndian.AppendUint16(nil, uint16(len(p.KDFInput))), []byte(p.KDFInput))
+		}
+		if p.KDF != 0 {
+			b = appendAttr(b, AttrKDF, binary.BigEndian.AppendUint16(nil, p.KDF), nil)
+		}
+		if p.MAC != nil {
+			b = appendAttr(b, AttrMAC, []byte{0, 0}, p.MAC)
+		}
+	}
+
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)))
+	return b
+}
+
+// appendAttr appends an attribute with the header and value padded to a multiple of 4 bytes.
+func appendAttr(b []byte, typ uint8, header, value []byte) []byte {
+	l := 2 + len(header) + len(value)
+	pad := (4 - l%4) % 4
+
+	b = append(b, typ, uint8((l+pad)/4))
+	b = append(b, header...)
+	b = append(b, value...)
+	return append(b, make([]byte, pad)...)
+}
+
+// Parse decodes an EAP packet. Unknown attributes are ignored.
+func Parse(b []byte) (*Packet, error) {
+	if len(b) < 4 {
+		return nil, errors.New("too short EAP packet")
+	}
+	l := int(binary.BigEndian.Uint16(b[2:4]))
+	if l < 4 || l > len(b) {
+		return nil, fmt.Errorf("invalid length of EAP packet: %d", l)
+	}
+	b = b[:l]
+
+	p := &Packet{Code: b[0], Identifier: b[1]}
+	if p.Code != CodeRequest && p.Code != CodeResponse {
+		return p, nil
+	}
+
+	if len(b) < 8 {
+		return nil, errors.New("too short EAP-AKA' packet")
+	}
+	if b[4] != TypeAKAPrime {
+		return nil, fmt.Errorf("unexpected EAP type: %d", b[4])
+	}
+	p.Subtype = b[5]
+
+	for off := 8; off < len(b); {
+		if len(b)-off < 4 {
+			return nil, errors.New("too short attribute")
+		}
+		typ, l := b[off], int(b[off+1])*4
+		if l == 0 || off+l > len(b) {
+			return nil, fmt.Errorf("invalid length of attribute %d: %d", typ, l)
+		}
+		v := b[off+2 : off+l]
+
+		var err error
+		switch typ {
+		case AttrRAND:
+			p.RAND, err = fixed(v, 2, 16)
+		case AttrAUTN:
+			p.AUTN, err = fixed(v, 2, 16)
+		case AttrMAC:
+			p.MAC, err = fixed(v, 2, 16)
+		case AttrAUTS:
+			p.AUTS, err = fixed(v, 0, 14)
+		case AttrRES:
+			p.RES, err = fixed(v, 2, int(binary.BigEndian.Uint16(v[:2]))/8)
+		case AttrKDFInput:
+			var s []byte
+			s, err = fixed(v, 2, int(binary.BigEndian.Uint16(v[:2])))
+			p.KDFInput = string(s)
+		case AttrKDF:
+			p.KDF = binary.BigEndian.Uint16(v[:2])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid attribute %d: %w", typ, err)
+		}
+
+		off += l
+	}
+	return p, nil
+}
+
+// fixed returns the value of l bytes after the reserved (or length) field.
+func fixed(v []byte, reserved, l int) ([]byte, error) {
+	if len(v) < reserved+l {
+		return nil, fmt.Errorf("length should be at least %d, got: %d", reserved+l, len(v))
+	}
+	return append([]byte(nil), v[reserved:reserved+l]...), nil
+}
+
+// Sign computes AT_MAC of p with K_aut, sets it in p and returns the byte sequence.
+func (p *Packet) Sign(kAut []byte) []byte {
+	p.MAC = make([]byte, 16)
+	b := p.Marshal()
+
+	// AT_MAC is always placed at the end of the packet by Marshal.
+	copy(b[len(b)-16:], computeMAC(kAut, b))
+	copy(p.MAC, b[len(b)-16:])
+	return b
+}
+
+// VerifyMAC verifies AT_MAC in the EAP packet b with K_aut.
+func VerifyMAC(b, kAut []byte) (bool, error) {
+	p, err := Parse(b)
+	if err != nil {
+		return false, err
+	}
+	if p.MAC == nil {
+		return false, errors.New("AT_MAC not found")
+	}
+
+	// find AT_MAC and zero the value to compute MAC.
+	zeroed := append([]byte(nil), b[:binary.BigEndian.Uint16(b[2:4])]...)
+	for off := 8; off+4 <= len(zeroed); off += int(zeroed[off+1]) * 4 {
+		if zeroed[off] == AttrMAC {
+			clear(zeroed[off+4 : off+20])
+			break
+		}
+		if zeroed[off+1] == 0 {
+			break
+		}
+	}
+	return hmac.Equal(computeMAC(kAut, zeroed), p.MAC), nil
+}
+
+// computeMAC computes HMAC-SHA-256-128 of b with K_aut.
+func computeMAC(kAut, b []byte) []byte {
+	mac := hmac.New(sha256.New, kAut)
+	mac.Write(b)
+	return mac.Sum(nil)[:16]
+}
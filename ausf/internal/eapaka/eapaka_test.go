@@ -0,0 +1,92 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package eapaka_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage/ausf/internal/eapaka"
+)
+
+func TestDeriveKeys(t *testing.T) {
+	// Test case 1 in Appendix C, RFC 5448.
+	keys := eapaka.DeriveKeys(
+		[]byte{0x00, 0x93, 0x96, 0x2d, 0x0d, 0xd8, 0x4a, 0xa5, 0x68, 0x4b, 0x04, 0x5c, 0x9e, 0xdf, 0xfa, 0x04},
+		[]byte{0xcc, 0xfc, 0x23, 0x0c, 0xa7, 0x4f, 0xcc, 0x96, 0xc0, 0xa5, 0xd6, 0x11, 0x64, 0xf5, 0xa7, 0x6c},
+		"0555444333222111",
+	)
+
+	if diff := cmp.Diff(keys.KEncr, []byte{0x76, 0x6f, 0xa0, 0xa6, 0xc3, 0x17, 0x17, 0x4b, 0x81, 0x2d, 0x52, 0xfb, 0xcd, 0x11, 0xa1, 0x79}); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(keys.KAut, []byte{
+		0x08, 0x42, 0xea, 0x72, 0x2f, 0xf6, 0x83, 0x5b, 0xfa, 0x20, 0x32, 0x49, 0x9f, 0xc3, 0xec, 0x23,
+		0xc2, 0xf0, 0xe3, 0x88, 0xb4, 0xf0, 0x75, 0x43, 0xff, 0xc6, 0x77, 0xf1, 0x69, 0x6d, 0x71, 0xea,
+	}); diff != "" {
+		t.Error(diff)
+	}
+	if len(keys.KRe) != 32 || len(keys.MSK) != 64 || len(keys.EMSK) != 64 {
+		t.Errorf("unexpected length of keys: %d, %d, %d", len(keys.KRe), len(keys.MSK), len(keys.EMSK))
+	}
+}
+
+func TestPacket(t *testing.T) {
+	kAut := []byte{
+		0x08, 0x42, 0xea, 0x72, 0x2f, 0xf6, 0x83, 0x5b, 0xfa, 0x20, 0x32, 0x49, 0x9f, 0xc3, 0xec, 0x23,
+		0xc2, 0xf0, 0xe3, 0x88, 0xb4, 0xf0, 0x75, 0x43, 0xff, 0xc6, 0x77, 0xf1, 0x69, 0x6d, 0x71, 0xea,
+	}
+
+	for _, p := range []*eapaka.Packet{
+		{
+			Code:       eapaka.CodeRequest,
+			Identifier: 1,
+			Subtype:    eapaka.SubtypeChallenge,
+			RAND:       []byte{0x81, 0xe9, 0x2b, 0x6c, 0x0e, 0xe0, 0xe1, 0x2e, 0xbc, 0xeb, 0xa8, 0xd9, 0x2a, 0x99, 0xdf, 0xa5},
+			AUTN:       []byte{0xbb, 0x52, 0xe9, 0x1c, 0x74, 0x7a, 0xc3, 0xab, 0x2a, 0x5c, 0x23, 0xd1, 0x5e, 0xe3, 0x51, 0xd5},
+			KDFInput:   "5G:mnc001.mcc001.3gppnetwork.org",
+			KDF:        eapaka.KDFDefault,
+		}, {
+			Code:       eapaka.CodeResponse,
+			Identifier: 1,
+			Subtype:    eapaka.SubtypeChallenge,
+			RES:        []byte{0x28, 0xd7, 0xb0, 0xf2, 0xa2, 0xec, 0x3d, 0xe5},
+		}, {
+			Code:       eapaka.CodeResponse,
+			Identifier: 2,
+			Subtype:    eapaka.SubtypeSynchronizationFailure,
+			AUTS:       []byte{0xba, 0x85, 0x3f, 0x3c, 0x12, 0x3c, 0xcf, 0x44, 0xe9, 0x35, 0x96, 0xe3, 0x55, 0xc6},
+			KDF:        eapaka.KDFDefault,
+		},
+	} {
+		b := p.Sign(kAut)
+		if len(b)%4 != 0 {
+			t.Errorf("unexpected length: %d", len(b))
+		}
+
+		got, err := eapaka.Parse(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, p); diff != "" {
+			t.Error(diff)
+		}
+
+		if ok, err := eapaka.VerifyMAC(b, kAut); err != nil || !ok {
+			t.Errorf("MAC verification failed: %v", err)
+		}
+		b[12] ^= 1
+		if ok, err := eapaka.VerifyMAC(b, kAut); err != nil || ok {
+			t.Errorf("tampered packet is expected to fail: %v", err)
+		}
+	}
+}
+
+func TestSuccess(t *testing.T) {
+	b := (&eapaka.Packet{Code: eapaka.CodeSuccess, Identifier: 3}).Marshal()
+	if diff := cmp.Diff(b, []byte{0x03, 0x03, 0x00, 0x04}); diff != "" {
+		t.Error(diff)
+	}
+}
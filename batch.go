@@ -0,0 +1,88 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Subscriber is a set of per-subscriber inputs used to generate vectors in batch.
+type Subscriber struct {
+	// K is a 128-bit subscriber key.
+	K [16]byte
+	// OPc is a 128-bit value derived from OP and K.
+	OPc [16]byte
+	// SQN is a 48-bit sequence number.
+	SQN [6]byte
+	// AMF is a 16-bit authentication management field.
+	AMF [2]byte
+}
+
+// BatchResult is the result of vector generation for a Subscriber.
+type BatchResult struct {
+	// RAND is the random challenge generated for the subscriber.
+	RAND [16]byte
+	// Vector is the set of values computed from the subscriber and RAND.
+	Vector Vector
+	// Err is the error occurred while processing the subscriber, if any.
+	Err error
+}
+
+// GenerateBatch generates a vector for each of subs with a new random RAND,
+// using at most workers goroutines. If workers is zero or negative,
+// runtime.GOMAXPROCS(0) is used.
+//
+// The returned slice has the same length as subs, and the i-th result corresponds
+// to subs[i]. Once ctx is done, the subscribers not processed yet are skipped and
+// their results have ctx.Err() as Err. GenerateBatch returns after all workers exit.
+func GenerateBatch(ctx context.Context, subs []Subscriber, workers int) []BatchResult {
+	results := make([]BatchResult, len(subs))
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(subs) {
+		workers = len(subs)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		next atomic.Int64
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(subs) {
+					return
+				}
+
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					continue
+				}
+				results[i].Err = generate(&subs[i], &results[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func generate(s *Subscriber, r *BatchResult) error {
+	if _, err := rand.Read(r.RAND[:]); err != nil {
+		return fmt.Errorf("failed to generate RAND: %w", err)
+	}
+
+	c := newCipher(&s.K, &s.OPc)
+	return c.Compute(&r.Vector, &r.RAND, &s.SQN, &s.AMF)
+}
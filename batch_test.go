@@ -0,0 +1,75 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+)
+
+func batchSubscribers(n int) []milenage.Subscriber {
+	subs := make([]milenage.Subscriber, n)
+	for i := range subs {
+		e := cases[i%len(cases)].expected.mil
+		copy(subs[i].K[:], e.K)
+		copy(subs[i].OPc[:], e.OPc)
+		copy(subs[i].SQN[:], e.SQN)
+		copy(subs[i].AMF[:], e.AMF)
+	}
+	return subs
+}
+
+func TestGenerateBatch(t *testing.T) {
+	subs := batchSubscribers(100)
+	results := milenage.GenerateBatch(context.Background(), subs, 4)
+	if len(results) != len(subs) {
+		t.Fatalf("unexpected number of results: %d", len(results))
+	}
+
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatalf("#%d failed: %v", i, r.Err)
+		}
+
+		s := subs[i]
+		m := milenage.NewWithOPc(s.K[:], s.OPc[:], r.RAND[:], milenage.SQNToUint64(s.SQN), uint16(s.AMF[0])<<8|uint16(s.AMF[1]))
+		if err := m.ComputeAll(); err != nil {
+			t.Fatal(err)
+		}
+		want, err := m.Vector()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(r.Vector, want); diff != "" {
+			t.Errorf("#%d failed: \n%s", i, diff)
+		}
+	}
+}
+
+func TestGenerateBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i, r := range milenage.GenerateBatch(ctx, batchSubscribers(10), 0) {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("#%d: unexpected error: %v", i, r.Err)
+		}
+	}
+}
+
+func BenchmarkGenerateBatch(b *testing.B) {
+	subs := batchSubscribers(1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		milenage.GenerateBatch(context.Background(), subs, 0)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import (
+	"crypto/aes"
+	"errors"
+	"fmt"
+)
+
+// Vector is a set of values computed by Cipher, held in fixed-size arrays.
+type Vector struct {
+	// MACA is the output of the function f1.
+	MACA [8]byte
+	// MACS is the output of the function f1*, computed with the same SQN and AMF as MACA.
+	MACS [8]byte
+	// RES is the output of the function f2.
+	RES [8]byte
+	// CK is the output of the function f3.
+	CK [16]byte
+	// IK is the output of the function f4.
+	IK [16]byte
+	// AK is the output of the function f5.
+	AK [6]byte
+	// AKS is the output of the function f5*. This is left untouched by ComputeQuintet.
+	AKS [6]byte
+	// AUTN is SQN xor AK || AMF || MAC-A.
+	AUTN [16]byte
+}
+
+// Cipher is a MILENAGE core bound to a single subscriber (K and OPc).
+//
+// Cipher expands the AES key schedule only once in NewCipher, computes TEMP only
+// once per RAND and writes the results into the caller-provided arrays, which makes
+// it suitable for generating lots of vectors without heap allocations.
+// Use NewCipherWithKernel instead to keep K behind Kernel.
+//
+// Cipher keeps its working buffers inside, so it must not be used by multiple
+// goroutines at the same time. Create one Cipher per goroutine instead.
+type Cipher struct {
+	kernel Kernel
+	opc    [16]byte
+
+	// working buffers.
+	temp [16]byte
+	in   [16]byte
+	out  [16]byte
+}
+
+// NewCipher creates a new Cipher from K and OPc.
+//
+// Use ComputeOPc beforehand if only OP is available.
+func NewCipher(k, opc []byte) (*Cipher, error) {
+	if len(k) != 16 {
+		return nil, fmt.Errorf("length of K should be %d, got: %d", 16, len(k))
+	}
+	if len(opc) != 16 {
+		return nil, fmt.Errorf("length of OPc should be %d, got: %d", 16, len(opc))
+	}
+
+	return newCipher((*[16]byte)(k), (*[16]byte)(opc)), nil
+}
+
+// NewCipherWithKernel creates a new Cipher from Kernel and OPc.
+//
+// Use ComputeOPcWithKernel beforehand if only OP is available.
+func NewCipherWithKernel(kernel Kernel, opc []byte) (*Cipher, error) {
+	if kernel == nil {
+		return nil, errors.New("kernel should not be nil")
+	}
+	if len(opc) != 16 {
+		return nil, fmt.Errorf("length of OPc should be %d, got: %d", 16, len(opc))
+	}
+	return &Cipher{kernel: kernel, opc: [16]byte(opc)}, nil
+}
+
+func newCipher(k, opc *[16]byte) *Cipher {
+	// aes.NewCipher never fails with a 128-bit key.
+	block, _ := aes.NewCipher(k[:])
+	return &Cipher{kernel: &SoftwareKernel{block: block}, opc: *opc}
+}
+
+// Compute computes all the values in Vector from RAND, SQN and AMF given.
+// This takes six AES block operations.
+//
+// The error is returned only when Kernel fails.
+func (c *Cipher) Compute(v *Vector, rand *[16]byte, sqn *[6]byte, amf *[2]byte) error {
+	if err := c.ComputeQuintet(v, rand, sqn, amf); err != nil {
+		return err
+	}
+
+	// TEMP is still available in c.temp.
+	if err := c.outN(4, 8); err != nil {
+		return err
+	}
+	copy(v.AKS[:], c.out[:6])
+	return nil
+}
+
+// ComputeQuintet computes all the values in Vector except AKS from RAND, SQN and AMF given.
+// This takes five AES block operations, which is the minimum to build an authentication quintet.
+//
+// The error is returned only when Kernel fails.
+func (c *Cipher) ComputeQuintet(v *Vector, rand *[16]byte, sqn *[6]byte, amf *[2]byte) error {
+	if err := c.computeTemp(rand); err != nil {
+		return err
+	}
+
+	if err := c.out1(sqn, amf); err != nil {
+		return err
+	}
+	copy(v.MACA[:], c.out[:8])
+	copy(v.MACS[:], c.out[8:])
+
+	// OUT2: rotate by r2=0, XOR on c2.
+	if err := c.outN(0, 1); err != nil {
+		return err
+	}
+	copy(v.RES[:], c.out[8:])
+	copy(v.AK[:], c.out[:6])
+
+	// OUT3: rotate by r3=32, XOR on c3.
+	if err := c.outN(12, 2); err != nil {
+		return err
+	}
+	copy(v.CK[:], c.out[:])
+
+	// OUT4: rotate by r4=64, XOR on c4.
+	if err := c.outN(8, 4); err != nil {
+		return err
+	}
+	copy(v.IK[:], c.out[:])
+
+	for i := 0; i < 6; i++ {
+		v.AUTN[i] = sqn[i] ^ v.AK[i]
+	}
+	copy(v.AUTN[6:8], amf[:])
+	copy(v.AUTN[8:], v.MACA[:])
+	return nil
+}
+
+// ComputeAUTS computes AUTS from RAND and SQNMS given, in the way described in
+// 6.3.3, TS 33.102. MAC-S is computed with AMF=0x0000.
+// This takes three AES block operations.
+//
+// The error is returned only when Kernel fails.
+func (c *Cipher) ComputeAUTS(auts *[14]byte, rand *[16]byte, sqnMS *[6]byte) error {
+	if err := c.computeTemp(rand); err != nil {
+		return err
+	}
+
+	if err := c.out1(sqnMS, &[2]byte{}); err != nil {
+		return err
+	}
+	copy(auts[6:], c.out[8:])
+
+	// OUT5: rotate by r5=96, XOR on c5.
+	if err := c.outN(4, 8); err != nil {
+		return err
+	}
+	for i := 0; i < 6; i++ {
+		auts[i] = sqnMS[i] ^ c.out[i]
+	}
+	return nil
+}
+
+// computeTemp computes TEMP = E[RAND xor OPc]K.
+func (c *Cipher) computeTemp(rand *[16]byte) error {
+	for i := 0; i < 16; i++ {
+		c.in[i] = rand[i] ^ c.opc[i]
+	}
+	return c.kernel.EncryptBlock(&c.temp, &c.in)
+}
+
+// out1 computes OUT1 into c.out, using TEMP computed beforehand.
+func (c *Cipher) out1(sqn *[6]byte, amf *[2]byte) error {
+	// IN1 = SQN || AMF || SQN || AMF, XOR OPc, rotate by r1=64,
+	// XOR on c1 (all zeroes) and XOR on TEMP.
+	for i := 0; i < 6; i++ {
+		c.in[i+8] = sqn[i] ^ c.opc[i]
+		c.in[i] = sqn[i] ^ c.opc[i+8]
+	}
+	for i := 0; i < 2; i++ {
+		c.in[i+14] = amf[i] ^ c.opc[i+6]
+		c.in[i+6] = amf[i] ^ c.opc[i+14]
+	}
+	for i := 0; i < 16; i++ {
+		c.in[i] ^= c.temp[i]
+	}
+
+	return c.encryptOut()
+}
+
+// outN computes OUT2-OUT5 into c.out, using TEMP computed beforehand.
+// rot is the rotation in bytes counted the same way as the other functions, and
+// cn is the last byte of the constant.
+func (c *Cipher) outN(rot int, cn byte) error {
+	for i := 0; i < 16; i++ {
+		c.in[(i+rot)%16] = c.temp[i] ^ c.opc[i]
+	}
+	c.in[15] ^= cn
+
+	return c.encryptOut()
+}
+
+// encryptOut encrypts c.in and XOR it on OPc into c.out.
+func (c *Cipher) encryptOut() error {
+	if err := c.kernel.EncryptBlock(&c.out, &c.in); err != nil {
+		return err
+	}
+	for i := 0; i < 16; i++ {
+		c.out[i] ^= c.opc[i]
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+)
+
+func newCipher(t testing.TB, m *milenage.Milenage) *milenage.Cipher {
+	t.Helper()
+
+	c, err := milenage.NewCipher(m.K, m.OPc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCipherCompute(t *testing.T) {
+	for _, c := range cases {
+		e := c.expected.mil
+		ci := newCipher(t, e)
+
+		var (
+			rand [16]byte
+			sqn  [6]byte
+			amf  [2]byte
+			got  milenage.Vector
+		)
+		copy(rand[:], e.RAND)
+		copy(sqn[:], e.SQN)
+		copy(amf[:], e.AMF)
+		if err := ci.Compute(&got, &rand, &sqn, &amf); err != nil {
+			t.Fatal(err)
+		}
+
+		want := milenage.Vector{}
+		copy(want.MACA[:], e.MACA)
+		copy(want.MACS[:], e.MACS)
+		copy(want.RES[:], e.RES)
+		copy(want.CK[:], e.CK)
+		copy(want.IK[:], e.IK)
+		copy(want.AK[:], e.AK)
+		copy(want.AKS[:], e.AKS)
+		copy(want.AUTN[:], c.expected.autn)
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+	}
+}
+
+func TestCipherComputeAUTS(t *testing.T) {
+	for _, c := range cases {
+		e := c.expected.mil
+		ci := newCipher(t, e)
+
+		var (
+			rand [16]byte
+			sqn  [6]byte
+			got  [14]byte
+		)
+		copy(rand[:], e.RAND)
+		copy(sqn[:], e.SQN)
+		if err := ci.ComputeAUTS(&got, &rand, &sqn); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(got[:], c.expected.auts); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+	}
+}
+
+func TestCipherAllocs(t *testing.T) {
+	e := cases[2].expected.mil
+	ci := newCipher(t, e)
+
+	var (
+		rand [16]byte
+		sqn  [6]byte
+		amf  [2]byte
+		v    milenage.Vector
+		auts [14]byte
+	)
+	if n := testing.AllocsPerRun(100, func() {
+		_ = ci.Compute(&v, &rand, &sqn, &amf)
+		_ = ci.ComputeAUTS(&auts, &rand, &sqn)
+	}); n != 0 {
+		t.Errorf("unexpected allocations: %v", n)
+	}
+}
+
+func BenchmarkCipherComputeQuintet(b *testing.B) {
+	e := cases[2].expected.mil
+	ci := newCipher(b, e)
+
+	var (
+		rand [16]byte
+		sqn  [6]byte
+		amf  [2]byte
+		v    milenage.Vector
+	)
+	copy(rand[:], e.RAND)
+	copy(sqn[:], e.SQN)
+	copy(amf[:], e.AMF)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ci.ComputeQuintet(&v, &rand, &sqn, &amf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMilenageF1F2345(b *testing.B) {
+	e := cases[2].expected.mil
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m := milenage.NewWithOPc(e.K, e.OPc, e.RAND, 0xff9bb4d0b607, 0xb9b9)
+		if _, err := m.F1(); err != nil {
+			b.Fatal(err)
+		}
+		if _, _, _, _, err := m.F2345(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package diameter provides the Diameter AVP (RFC 6733) encoding and decoding shared by
+the packages for the 3GPP Diameter applications, e.g., s6a and swx.
+
+Only the AVPs are handled here, and the Diameter header and the messages are out of the
+scope of this package.
+*/
+package diameter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// VendorID3GPP is the Vendor-Id of 3GPP.
+const VendorID3GPP uint32 = 10415
+
+// AVP flags.
+const (
+	FlagVendor    uint8 = 0x80
+	FlagMandatory uint8 = 0x40
+)
+
+// AVP is a Diameter AVP defined in 4.1, RFC 6733.
+type AVP struct {
+	Code     uint32
+	Flags    uint8
+	VendorID uint32
+	Data     []byte
+}
+
+// NewAVP creates a new AVP of 3GPP with V and M bits set, which is the case for
+// all the authentication related AVPs of 3GPP.
+func NewAVP(code uint32, data []byte) *AVP {
+	return &AVP{Code: code, Flags: FlagVendor | FlagMandatory, VendorID: VendorID3GPP, Data: data}
+}
+
+// MarshalLen returns the length of a in bytes, including padding.
+func (a *AVP) MarshalLen() int {
+	return (a.len() + 3) &^ 3
+}
+
+func (a *AVP) len() int {
+	l := 8 + len(a.Data)
+	if a.Flags&FlagVendor != 0 {
+		l += 4
+	}
+	return l
+}
+
+// Marshal returns the byte sequence of a, padded to a multiple of 4 bytes.
+func (a *AVP) Marshal() []byte {
+	return a.AppendTo(make([]byte, 0, a.MarshalLen()))
+}
+
+// AppendTo appends the byte sequence of a to b.
+func (a *AVP) AppendTo(b []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, a.Code)
+	b = binary.BigEndian.AppendUint32(b, uint32(a.Flags)<<24|uint32(a.len()))
+	if a.Flags&FlagVendor != 0 {
+		b = binary.BigEndian.AppendUint32(b, a.VendorID)
+	}
+	b = append(b, a.Data...)
+	for i := a.len(); i < a.MarshalLen(); i++ {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// ParseAVP parses the first AVP in b, and returns it with the rest of b.
+// Data of the AVP returned refers to b.
+func ParseAVP(b []byte) (*AVP, []byte, error) {
+	if len(b) < 8 {
+		return nil, nil, errors.New("too short for AVP header")
+	}
+
+	a := &AVP{Code: binary.BigEndian.Uint32(b[0:4]), Flags: b[4]}
+	l := int(binary.BigEndian.Uint32(b[4:8]) & 0xffffff)
+	off := 8
+	if a.Flags&FlagVendor != 0 {
+		if len(b) < 12 {
+			return nil, nil, errors.New("too short for AVP header with Vendor-Id")
+		}
+		a.VendorID = binary.BigEndian.Uint32(b[8:12])
+		off = 12
+	}
+	if l < off || l > len(b) {
+		return nil, nil, fmt.Errorf("invalid length of AVP %d: %d", a.Code, l)
+	}
+	a.Data = b[off:l]
+
+	// The padding may be omitted in the last AVP.
+	next := min((l+3)&^3, len(b))
+	return a, b[next:], nil
+}
+
+// ParseAVPs parses all the AVPs in b, e.g., the Data of a Grouped AVP.
+func ParseAVPs(b []byte) ([]*AVP, error) {
+	var avps []*AVP
+	for len(b) > 0 {
+		a, rest, err := ParseAVP(b)
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, a)
+		b = rest
+	}
+	return avps, nil
+}
+
+// NewGroupedAVP creates a new Grouped AVP of 3GPP with avps inside.
+func NewGroupedAVP(code uint32, avps ...*AVP) *AVP {
+	l := 0
+	for _, a := range avps {
+		l += a.MarshalLen()
+	}
+	data := make([]byte, 0, l)
+	for _, a := range avps {
+		data = a.AppendTo(data)
+	}
+	return NewAVP(code, data)
+}
+
+// NewUnsigned32AVP creates a new Unsigned32 AVP of 3GPP.
+func NewUnsigned32AVP(code, v uint32) *AVP {
+	return NewAVP(code, binary.BigEndian.AppendUint32(nil, v))
+}
+
+// Unsigned32 decodes the Data of a as Unsigned32.
+func (a *AVP) Unsigned32() (uint32, error) {
+	if len(a.Data) != 4 {
+		return 0, fmt.Errorf("invalid length of Unsigned32 AVP %d: %d", a.Code, len(a.Data))
+	}
+	return binary.BigEndian.Uint32(a.Data), nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package diameter_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage/diameter"
+)
+
+func TestAVP(t *testing.T) {
+	a := &diameter.AVP{Code: 1, Flags: diameter.FlagMandatory, Data: []byte{0x01, 0x02, 0x03}}
+	b := a.Marshal()
+	if diff := cmp.Diff(b, []byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x00, 0x00, 0x0b, 0x01, 0x02, 0x03, 0x00}); diff != "" {
+		t.Error(diff)
+	}
+
+	got, rest, err := diameter.ParseAVP(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, a); diff != "" {
+		t.Error(diff)
+	}
+	if len(rest) != 0 {
+		t.Errorf("unexpected rest: %x", rest)
+	}
+
+	for _, b := range [][]byte{
+		{0x00, 0x00, 0x00, 0x01, 0x40, 0x00, 0x00},
+		{0x00, 0x00, 0x00, 0x01, 0x40, 0x00, 0x00, 0x10, 0x01, 0x02, 0x03},
+		{0x00, 0x00, 0x00, 0x01, 0xc0, 0x00, 0x00, 0x0b, 0x00, 0x00, 0x28},
+	} {
+		if _, _, err := diameter.ParseAVP(b); err == nil {
+			t.Errorf("expected error with %x", b)
+		}
+	}
+}
+
+func TestGroupedAVP(t *testing.T) {
+	a := diameter.NewGroupedAVP(1413, diameter.NewUnsigned32AVP(1419, 1))
+	b := a.Marshal()
+	if diff := cmp.Diff(b, []byte{
+		0x00, 0x00, 0x05, 0x85, 0xc0, 0x00, 0x00, 0x1c, 0x00, 0x00, 0x28, 0xaf, 0x00, 0x00, 0x05, 0x8b,
+		0xc0, 0x00, 0x00, 0x10, 0x00, 0x00, 0x28, 0xaf, 0x00, 0x00, 0x00, 0x01,
+	}); diff != "" {
+		t.Error(diff)
+	}
+
+	got, _, err := diameter.ParseAVP(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	avps, err := diameter.ParseAVPs(got.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(avps) != 1 {
+		t.Fatalf("unexpected AVPs: %+v", avps)
+	}
+	if v, err := avps[0].Unsigned32(); err != nil || v != 1 {
+		t.Errorf("unexpected value: %d, %v", v, err)
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package digestaka provides HTTP Digest AKA (RFC 3310 for AKAv1 and RFC 4169 for AKAv2) used
+in IMS, which are the nonce built from RAND and AUTN, the passwords derived from RES, CK and IK,
+the Digest response, and the SIP WWW-Authenticate and Authorization headers.
+*/
+package digestaka
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/wmnsk/milenage"
+)
+
+// Algorithm definitions.
+const (
+	AlgorithmAKAv1MD5 = "AKAv1-MD5"
+	AlgorithmAKAv2MD5 = "AKAv2-MD5"
+)
+
+// QOP definitions.
+const (
+	QOPAuth    = "auth"
+	QOPAuthInt = "auth-int"
+)
+
+// Nonce builds the nonce as base64(RAND || AUTN || server-data) (3.2, RFC 3310).
+// serverData can be nil.
+func Nonce(rand, autn, serverData []byte) (string, error) {
+	if len(rand) != 16 {
+		return "", fmt.Errorf("length of RAND should be %d, got: %d", 16, len(rand))
+	}
+	if len(autn) != 16 {
+		return "", fmt.Errorf("length of AUTN should be %d, got: %d", 16, len(autn))
+	}
+
+	b := make([]byte, 0, 32+len(serverData))
+	b = append(append(append(b, rand...), autn...), serverData...)
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// NewNonce builds the nonce from RAND and AUTN generated with the values in m.
+//
+// Note that this function should be called after F1 and F2345 are done.
+func NewNonce(m *milenage.Milenage, serverData []byte) (string, error) {
+	autn, err := m.GenerateAUTN()
+	if err != nil {
+		return "", err
+	}
+	return Nonce(m.RAND, autn, serverData)
+}
+
+// ParseNonce retrieves RAND, AUTN and server-data from nonce.
+func ParseNonce(nonce string) (rand, autn, serverData []byte, err error) {
+	b, err := base64.StdEncoding.DecodeString(nonce)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	if len(b) < 32 {
+		return nil, nil, nil, fmt.Errorf("too short nonce: %d", len(b))
+	}
+	return b[:16], b[16:32], b[32:], nil
+}
+
+// Password returns the password used to compute the Digest response with algorithm.
+//
+// For AKAv1, the password is RES (3.3, RFC 3310). For AKAv2, the password is
+// base64(PRF(RES || IK || CK, "http-digest-akav2-password")), where PRF is HMAC-MD5
+// (3, RFC 4169).
+//
+// On synchronisation failure, give nil to res, ik and ck to get the password used
+// with auts, which is empty for AKAv1 (3.4, RFC 3310).
+func Password(algorithm string, res, ik, ck []byte) ([]byte, error) {
+	switch algorithm {
+	case AlgorithmAKAv1MD5:
+		return append([]byte{}, res...), nil
+	case AlgorithmAKAv2MD5:
+		key := make([]byte, 0, len(res)+len(ik)+len(ck))
+		key = append(append(append(key, res...), ik...), ck...)
+		mac := hmac.New(md5.New, key)
+		mac.Write([]byte("http-digest-akav2-password"))
+		return []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil))), nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+}
+
+// Challenge is the Digest challenge in the WWW-Authenticate (or Proxy-Authenticate) header.
+type Challenge struct {
+	Realm     string
+	Nonce     string
+	Algorithm string
+	QOP       []string
+	Opaque    string
+	Stale     bool
+}
+
+// NewChallenge creates a new Challenge with the nonce built from the values in m.
+//
+// Note that this function should be called after F1 and F2345 are done.
+func NewChallenge(m *milenage.Milenage, realm, algorithm string) (*Challenge, error) {
+	nonce, err := NewNonce(m, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Challenge{Realm: realm, Nonce: nonce, Algorithm: algorithm, QOP: []string{QOPAuth, QOPAuthInt}}, nil
+}
+
+// String returns c as the value of WWW-Authenticate header.
+func (c *Challenge) String() string {
+	var p params
+	p.quoted("realm", c.Realm)
+	p.quoted("nonce", c.Nonce)
+	if c.Algorithm != "" {
+		p.token("algorithm", c.Algorithm)
+	}
+	if len(c.QOP) > 0 {
+		p.quoted("qop", strings.Join(c.QOP, ","))
+	}
+	if c.Opaque != "" {
+		p.quoted("opaque", c.Opaque)
+	}
+	if c.Stale {
+		p.token("stale", "true")
+	}
+	return p.String()
+}
+
+// ParseChallenge parses the value of WWW-Authenticate header.
+func ParseChallenge(s string) (*Challenge, error) {
+	m, err := parseParams(s)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Challenge{
+		Realm:     m["realm"],
+		Nonce:     m["nonce"],
+		Algorithm: m["algorithm"],
+		Opaque:    m["opaque"],
+		Stale:     strings.EqualFold(m["stale"], "true"),
+	}
+	if q := m["qop"]; q != "" {
+		for _, v := range strings.Split(q, ",") {
+			c.QOP = append(c.QOP, strings.TrimSpace(v))
+		}
+	}
+	if c.Nonce == "" {
+		return nil, fmt.Errorf("missing nonce in challenge: %s", s)
+	}
+	return c, nil
+}
+
+// Credentials is the Digest credentials in the Authorization (or Proxy-Authorization) header.
+type Credentials struct {
+	Username  string
+	Realm     string
+	Nonce     string
+	URI       string
+	Response  string
+	Algorithm string
+	CNonce    string
+	Opaque    string
+	QOP       string
+	NC        string
+	// AUTS is sent on synchronisation failure, which is base64 encoded in the header.
+	AUTS []byte
+}
+
+// NewCredentials creates a new Credentials in response to c, with qop=auth if offered.
+// Call Sign to compute the response.
+func NewCredentials(c *Challenge, username, uri string) *Credentials {
+	cr := &Credentials{
+		Username:  username,
+		Realm:     c.Realm,
+		Nonce:     c.Nonce,
+		URI:       uri,
+		Algorithm: c.Algorithm,
+		Opaque:    c.Opaque,
+	}
+	for _, q := range c.QOP {
+		if q == QOPAuth {
+			cr.QOP = QOPAuth
+		}
+	}
+	return cr
+}
+
+// ComputeResponse computes the Digest response defined in 3.2.2, RFC 2617, with password
+// and the request method (and body if qop is auth-int).
+func (c *Credentials) ComputeResponse(password []byte, method string, body []byte) string {
+	ha1 := md5hex([]byte(c.Username), []byte(c.Realm), password)
+
+	ha2 := md5hex([]byte(method), []byte(c.URI))
+	if c.QOP == QOPAuthInt {
+		ha2 = md5hex([]byte(method), []byte(c.URI), []byte(md5hex(body)))
+	}
+
+	if c.QOP == "" {
+		return md5hex([]byte(ha1), []byte(c.Nonce), []byte(ha2))
+	}
+	return md5hex([]byte(ha1), []byte(c.Nonce), []byte(c.NC), []byte(c.CNonce), []byte(c.QOP), []byte(ha2))
+}
+
+// Sign computes the response with password and sets it to c. CNonce and NC are
+// filled with a random value and "00000001" if qop is set and they are empty.
+func (c *Credentials) Sign(password []byte, method string, body []byte) error {
+	if c.QOP != "" {
+		if c.CNonce == "" {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				return err
+			}
+			c.CNonce = hex.EncodeToString(b)
+		}
+		if c.NC == "" {
+			c.NC = "00000001"
+		}
+	}
+	c.Response = c.ComputeResponse(password, method, body)
+	return nil
+}
+
+// Verify verifies the response in c with password.
+func (c *Credentials) Verify(password []byte, method string, body []byte) bool {
+	want := c.ComputeResponse(password, method, body)
+	return subtle.ConstantTimeCompare([]byte(strings.ToLower(c.Response)), []byte(want)) == 1
+}
+
+// String returns c as the value of Authorization header.
+func (c *Credentials) String() string {
+	var p params
+	p.quoted("username", c.Username)
+	p.quoted("realm", c.Realm)
+	p.quoted("nonce", c.Nonce)
+	p.quoted("uri", c.URI)
+	p.quoted("response", c.Response)
+	if c.Algorithm != "" {
+		p.token("algorithm", c.Algorithm)
+	}
+	if c.CNonce != "" {
+		p.quoted("cnonce", c.CNonce)
+	}
+	if c.Opaque != "" {
+		p.quoted("opaque", c.Opaque)
+	}
+	if c.QOP != "" {
+		p.token("qop", c.QOP)
+		p.token("nc", c.NC)
+	}
+	if c.AUTS != nil {
+		p.quoted("auts", base64.StdEncoding.EncodeToString(c.AUTS))
+	}
+	return p.String()
+}
+
+// ParseCredentials parses the value of Authorization header.
+func ParseCredentials(s string) (*Credentials, error) {
+	m, err := parseParams(s)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Credentials{
+		Username:  m["username"],
+		Realm:     m["realm"],
+		Nonce:     m["nonce"],
+		URI:       m["uri"],
+		Response:  m["response"],
+		Algorithm: m["algorithm"],
+		CNonce:    m["cnonce"],
+		Opaque:    m["opaque"],
+		QOP:       m["qop"],
+		NC:        m["nc"],
+	}
+	if auts, ok := m["auts"]; ok {
+		if c.AUTS, err = base64.StdEncoding.DecodeString(auts); err != nil {
+			return nil, fmt.Errorf("invalid auts: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func md5hex(values ...[]byte) string {
+	h := md5.New()
+	for i, v := range values {
+		if i > 0 {
+			h.Write([]byte{':'})
+		}
+		h.Write(v)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
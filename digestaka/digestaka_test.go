@@ -0,0 +1,274 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package digestaka_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/digestaka"
+)
+
+func TestNonce(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, err := digestaka.NewNonce(m, []byte("server"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rand, autn, serverData, err := digestaka.ParseNonce(nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(rand, m.RAND); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(autn, []byte{0x55, 0xf3, 0x28, 0xb4, 0x35, 0x77, 0xb9, 0xb9, 0x4a, 0x9f, 0xfa, 0xc3, 0x54, 0xdf, 0xaf, 0xb3}); diff != "" {
+		t.Error(diff)
+	}
+	if string(serverData) != "server" {
+		t.Errorf("unexpected server-data: %s", serverData)
+	}
+
+	if _, _, _, err := digestaka.ParseNonce("AAAA"); err == nil {
+		t.Error("expected error with short nonce")
+	}
+}
+
+func TestPassword(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		algorithm string
+		want      []byte
+	}{
+		{digestaka.AlgorithmAKAv1MD5, m.RES},
+		{digestaka.AlgorithmAKAv2MD5, []byte("shzt3q8CWaZnCAWqs3WmEQ==")},
+	} {
+		got, err := digestaka.Password(c.algorithm, m.RES, m.IK, m.CK)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, c.want); diff != "" {
+			t.Errorf("%s failed: \n%s", c.algorithm, diff)
+		}
+	}
+
+	if _, err := digestaka.Password("MD5", m.RES, m.IK, m.CK); err == nil {
+		t.Error("expected error with unsupported algorithm")
+	}
+}
+
+func TestComputeResponse(t *testing.T) {
+	// Example in 3.5, RFC 2617.
+	c := &digestaka.Credentials{
+		Username: "Mufasa",
+		Realm:    "testrealm@host.com",
+		Nonce:    "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+		URI:      "/dir/index.html",
+		CNonce:   "0a4f113b",
+		QOP:      digestaka.QOPAuth,
+		NC:       "00000001",
+	}
+	if got := c.ComputeResponse([]byte("Circle Of Life"), "GET", nil); got != "6629fae49393a05397450978507c4ef1" {
+		t.Errorf("unexpected response: %s", got)
+	}
+}
+
+func TestChallengeResponse(t *testing.T) {
+	const (
+		realm    = "ims.mnc001.mcc001.3gppnetwork.org"
+		username = "001010000000001@" + realm
+		uri      = "sip:" + realm
+	)
+
+	for _, alg := range []string{digestaka.AlgorithmAKAv1MD5, digestaka.AlgorithmAKAv2MD5} {
+		// S-CSCF sends the challenge.
+		m := milenage.NewWithOPc(
+			[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+			[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+			[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+			0xff9bb4d0b607,
+			0xb9b9,
+		)
+		if err := m.ComputeAll(); err != nil {
+			t.Fatal(err)
+		}
+		ch, err := digestaka.NewChallenge(m, realm, alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch.Opaque = "op\"aque"
+
+		got, err := digestaka.ParseChallenge(ch.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, ch); diff != "" {
+			t.Errorf("%s failed: \n%s", alg, diff)
+		}
+
+		// UE computes RES, CK and IK from RAND in the nonce, and responds.
+		rand, _, _, err := digestaka.ParseNonce(got.Nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ue := milenage.NewWithOPc(m.K, m.OPc, rand, 0, 0)
+		res, ck, ik, _, err := ue.F2345()
+		if err != nil {
+			t.Fatal(err)
+		}
+		password, err := digestaka.Password(got.Algorithm, res, ik, ck)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cr := digestaka.NewCredentials(got, username, uri)
+		if err := cr.Sign(password, "REGISTER", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// S-CSCF verifies the response with XRES.
+		gotCr, err := digestaka.ParseCredentials(cr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(gotCr, cr); diff != "" {
+			t.Errorf("%s failed: \n%s", alg, diff)
+		}
+		xpassword, err := digestaka.Password(alg, m.RES, m.IK, m.CK)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !gotCr.Verify(xpassword, "REGISTER", nil) {
+			t.Errorf("%s failed: response verification failed", alg)
+		}
+		if gotCr.Verify(xpassword, "INVITE", nil) {
+			t.Errorf("%s failed: response with wrong method is expected to fail", alg)
+		}
+	}
+}
+
+func TestCredentialsAUTS(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+	ch, err := digestaka.NewChallenge(m, "ims.example.com", digestaka.AlgorithmAKAv1MD5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// UE has SQNMS=0x1000 and rejects the AUTN received, with an empty password.
+	ue := milenage.NewWithOPc(m.K, m.OPc, m.RAND, 0x1000, 0)
+	auts, err := ue.GenerateAUTS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := digestaka.NewCredentials(ch, "user@ims.example.com", "sip:ims.example.com")
+	cr.AUTS = auts
+	password, err := digestaka.Password(cr.Algorithm, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cr.Sign(password, "REGISTER", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := digestaka.ParseCredentials(cr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Verify(nil, "REGISTER", nil) {
+		t.Error("response verification failed")
+	}
+
+	sqnMS, ok, err := m.VerifyAUTS(got.AUTS)
+	if err != nil || !ok {
+		t.Fatalf("AUTS verification failed: %v, %v", ok, err)
+	}
+	if sqn := milenage.SQNToUint64([6]byte(sqnMS)); sqn != 0x1000 {
+		t.Errorf("unexpected SQNMS: %x", sqn)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	s := `Digest username="user@ims.example.com",realm="ims.example.com", nonce="abc",` +
+		` uri="sip:ims.example.com", response="6629fae49393a05397450978507c4ef1", algorithm=AKAv1-MD5,` +
+		` cnonce="0a4f113b", qop=auth-int, nc=00000002, auts="uoU/PBI8z0TpNZbjVcY="`
+	got, err := digestaka.ParseCredentials(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &digestaka.Credentials{
+		Username:  "user@ims.example.com",
+		Realm:     "ims.example.com",
+		Nonce:     "abc",
+		URI:       "sip:ims.example.com",
+		Response:  "6629fae49393a05397450978507c4ef1",
+		Algorithm: digestaka.AlgorithmAKAv1MD5,
+		CNonce:    "0a4f113b",
+		QOP:       digestaka.QOPAuthInt,
+		NC:        "00000002",
+		AUTS:      []byte{0xba, 0x85, 0x3f, 0x3c, 0x12, 0x3c, 0xcf, 0x44, 0xe9, 0x35, 0x96, 0xe3, 0x55, 0xc6},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, s := range []string{
+		`Basic realm="ims.example.com"`,
+		`Digest realm="ims.example.com, nonce="abc`,
+		`Digest realm`,
+		`Digestxyz realm="ims.example.com"`,
+	} {
+		if _, err := digestaka.ParseCredentials(s); err == nil {
+			t.Errorf("expected error with %s", s)
+		}
+	}
+	if _, err := digestaka.ParseChallenge(`Digest realm="ims.example.com"`); err == nil {
+		t.Error("expected error without nonce")
+	}
+}
+
+func TestChallengeWithoutAlgorithm(t *testing.T) {
+	c := &digestaka.Challenge{Realm: "ims.example.com", Nonce: "abc"}
+	s := c.String()
+	if s != `Digest realm="ims.example.com", nonce="abc"` {
+		t.Errorf("unexpected challenge: %s", s)
+	}
+
+	got, err := digestaka.ParseChallenge(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, c); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package digestaka
+
+import (
+	"fmt"
+	"strings"
+)
+
+const scheme = "Digest"
+
+// params builds the auth-params of Digest scheme.
+type params struct {
+	sb strings.Builder
+}
+
+func (p *params) sep() {
+	if p.sb.Len() == 0 {
+		p.sb.WriteString(scheme + " ")
+		return
+	}
+	p.sb.WriteString(", ")
+}
+
+func (p *params) token(key, value string) {
+	p.sep()
+	p.sb.WriteString(key + "=" + value)
+}
+
+func (p *params) quoted(key, value string) {
+	p.sep()
+	p.sb.WriteString(key + "=\"")
+	for _, c := range value {
+		if c == '"' || c == '\\' {
+			p.sb.WriteByte('\\')
+		}
+		p.sb.WriteRune(c)
+	}
+	p.sb.WriteByte('"')
+}
+
+func (p *params) String() string {
+	return p.sb.String()
+}
+
+// parseParams parses the auth-params of Digest scheme in s into a map with
+// the keys in lower case.
+func parseParams(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < len(scheme) || !strings.EqualFold(s[:len(scheme)], scheme) {
+		return nil, fmt.Errorf("not a Digest scheme: %s", s)
+	}
+	s = s[len(scheme):]
+	// the scheme should be followed by whitespace, not to accept e.g. "Digestxyz".
+	if s != "" && s[0] != ' ' && s[0] != '\t' {
+		return nil, fmt.Errorf("not a Digest scheme: %s%s", scheme, s)
+	}
+
+	m := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return m, nil
+		}
+
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid auth-param: %s", s)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " \t")
+
+		var value strings.Builder
+		if strings.HasPrefix(rest, "\"") {
+			i, escaped, closed := 1, false, false
+			for ; i < len(rest); i++ {
+				c := rest[i]
+				if escaped {
+					value.WriteByte(c)
+					escaped = false
+					continue
+				}
+				if c == '\\' {
+					escaped = true
+					continue
+				}
+				if c == '"' {
+					closed = true
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated quoted-string in %s", key)
+			}
+			s = rest[i+1:]
+		} else {
+			v, r, _ := strings.Cut(rest, ",")
+			value.WriteString(strings.TrimSpace(v))
+			s = r
+		}
+		m[key] = value.String()
+	}
+}
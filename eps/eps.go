@@ -0,0 +1,134 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package eps provides the key derivation of the EPS key hierarchy below KASME (Annex A,
+TS 33.401), which are KeNB, NH, KeNB* and the algorithm keys for NAS, RRC and UP.
+
+KASME can be computed with milenage.Milenage.ComputeKASME from CK and IK produced by F2345.
+*/
+package eps
+
+import (
+	"encoding/binary"
+
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/internal/keyderiv"
+)
+
+// FC definitions (Annex A, TS 33.401).
+const (
+	fcKeNB         = 0x11
+	fcNH           = 0x12
+	fcKeNBStar     = 0x13
+	fcAlgorithmKey = 0x15
+)
+
+// Algorithm type distinguisher definitions (A.7, TS 33.401).
+const (
+	NASEncAlg uint8 = 0x01
+	NASIntAlg uint8 = 0x02
+	RRCEncAlg uint8 = 0x03
+	RRCIntAlg uint8 = 0x04
+	UPEncAlg  uint8 = 0x05
+	UPIntAlg  uint8 = 0x06
+)
+
+// Algorithm identity definitions (5.1.3.2 and 5.1.4.2, TS 33.401).
+const (
+	EEA0 uint8 = 0
+	EEA1 uint8 = 1
+	EEA2 uint8 = 2
+	EEA3 uint8 = 3
+
+	EIA0 uint8 = 0
+	EIA1 uint8 = 1
+	EIA2 uint8 = 2
+	EIA3 uint8 = 3
+)
+
+// KeNB derives KeNB from KASME and the uplink NAS COUNT (A.3, TS 33.401).
+func KeNB(kasme []byte, ulNASCount uint32) []byte {
+	return milenage.KDF(kasme, fcKeNB, binary.BigEndian.AppendUint32(nil, ulNASCount))
+}
+
+// NH derives NH from KASME and SYNC-input, which is the KeNB derived at the initial
+// context setup or the previous NH (A.4, TS 33.401).
+func NH(kasme, syncInput []byte) []byte {
+	return milenage.KDF(kasme, fcNH, syncInput)
+}
+
+// KeNBStar derives KeNB* for handover from key, which is either the current KeNB
+// (horizontal derivation) or NH (vertical derivation), with PCI and EARFCN-DL of
+// the target cell (A.5, TS 33.401).
+//
+// EARFCN-DL is encoded in 3 octets if it is larger than 65535, or 2 octets otherwise.
+func KeNBStar(key []byte, pci uint16, earfcnDL uint32) []byte {
+	earfcn := binary.BigEndian.AppendUint32(nil, earfcnDL)[1:]
+	if earfcnDL <= 0xffff {
+		earfcn = earfcn[1:]
+	}
+	return milenage.KDF(key, fcKeNBStar, binary.BigEndian.AppendUint16(nil, pci), earfcn)
+}
+
+// AlgorithmKey derives the 128-bit key for the algorithm identified by the algorithm type
+// distinguisher and the algorithm identity from key, which is KASME for NAS and KeNB for
+// RRC and UP (A.7, TS 33.401). The 128 least significant bits of the KDF output are used.
+func AlgorithmKey(key []byte, distinguisher, algID uint8) []byte {
+	return keyderiv.AlgorithmKey(key, fcAlgorithmKey, distinguisher, algID)
+}
+
+// KNASenc derives KNASenc from KASME for the EEA algorithm identity.
+func KNASenc(kasme []byte, eea uint8) []byte {
+	return AlgorithmKey(kasme, NASEncAlg, eea)
+}
+
+// KNASint derives KNASint from KASME for the EIA algorithm identity.
+func KNASint(kasme []byte, eia uint8) []byte {
+	return AlgorithmKey(kasme, NASIntAlg, eia)
+}
+
+// KRRCenc derives KRRCenc from KeNB for the EEA algorithm identity.
+func KRRCenc(kenb []byte, eea uint8) []byte {
+	return AlgorithmKey(kenb, RRCEncAlg, eea)
+}
+
+// KRRCint derives KRRCint from KeNB for the EIA algorithm identity.
+func KRRCint(kenb []byte, eia uint8) []byte {
+	return AlgorithmKey(kenb, RRCIntAlg, eia)
+}
+
+// KUPenc derives KUPenc from KeNB for the EEA algorithm identity.
+func KUPenc(kenb []byte, eea uint8) []byte {
+	return AlgorithmKey(kenb, UPEncAlg, eea)
+}
+
+// KUPint derives KUPint from KeNB for the EIA algorithm identity.
+func KUPint(kenb []byte, eia uint8) []byte {
+	return AlgorithmKey(kenb, UPIntAlg, eia)
+}
+
+// NHChain keeps the NH and NCC pair for handover (7.2.8.1, TS 33.401).
+//
+// NHChain is not safe for concurrent use.
+type NHChain struct {
+	chain *keyderiv.NHChain
+}
+
+// NewNHChain creates a new NHChain at the initial context setup, where the initial
+// KeNB is derived from KASME and NCC is zero.
+func NewNHChain(kasme, kenb []byte) *NHChain {
+	return &NHChain{chain: keyderiv.NewNHChain(fcNH, kasme, kenb)}
+}
+
+// NH returns the current NH and NCC. NH is the initial KeNB while NCC is zero.
+func (c *NHChain) NH() (nh []byte, ncc uint8) {
+	return c.chain.NH()
+}
+
+// Next derives the next NH from KASME and the current NH as NH does, and returns it
+// with NCC incremented. NCC is 3 bits and wraps around to zero after seven.
+func (c *NHChain) Next() (nh []byte, ncc uint8) {
+	return c.chain.Next()
+}
@@ -0,0 +1,94 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package eps_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/eps"
+)
+
+func computeKASME(t *testing.T) []byte {
+	t.Helper()
+
+	b := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	m := milenage.New(b, b, b, 1, 0x8000)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+	kasme, err := m.ComputeKASME("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kasme
+}
+
+// TS 33.401 has no test data for the derivations below KASME. The expected values here
+// are generated by this package itself, and only guard against regressions.
+func TestKeys(t *testing.T) {
+	kasme := computeKASME(t)
+	kenb := eps.KeNB(kasme, 0)
+
+	for _, c := range []struct {
+		description string
+		got         []byte
+		want        string
+	}{
+		{"KeNB", kenb, "cd6fa7e99bb763d619f4c817c810477ade59cf1d71aca8fa1e6d392508e4e809"},
+		{"KNASenc", eps.KNASenc(kasme, eps.EEA2), "2aadf4a011bf89753a98c68fb5d804c5"},
+		{"KNASint", eps.KNASint(kasme, eps.EIA2), "dea14d16f48bc8959074052b112c7cb4"},
+		{"KRRCint", eps.KRRCint(kenb, eps.EIA2), "3d02f4a8185169b975e2977446cd6d45"},
+		{"KUPenc", eps.KUPenc(kenb, eps.EEA1), "356372bd242ca2a3eaf0d9bb58d02779"},
+		{"KRRCenc", eps.KRRCenc(kenb, eps.EEA2), "fb7230b63ccf4ab03d853f901e51f6ca"},
+		{"KeNB*", eps.KeNBStar(kenb, 1, 1575), "aa115d13acdde921a14ca5b42d3ac6f0aa837c0a2c243f4703dcb9863e7af7ec"},
+	} {
+		if diff := cmp.Diff(hex.EncodeToString(c.got), c.want); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+	}
+}
+
+func TestNHChain(t *testing.T) {
+	kasme := computeKASME(t)
+	kenb := eps.KeNB(kasme, 0)
+	c := eps.NewNHChain(kasme, kenb)
+
+	if nh, ncc := c.NH(); ncc != 0 || !cmp.Equal(nh, kenb) {
+		t.Errorf("unexpected initial NH and NCC: %x, %d", nh, ncc)
+	}
+
+	for i, want := range []string{
+		"ecb8d224aa46d4b48d9db7bfb1350679334eeb4467242e1d28fba0f9cb111ec9",
+		"3c34282ed7e56f15adecd2ae03d013d787bc02119f2638bae1a4228987331374",
+	} {
+		nh, ncc := c.Next()
+		if diff := cmp.Diff(hex.EncodeToString(nh), want); diff != "" {
+			t.Errorf("NH%d failed: \n%s", i+1, diff)
+		}
+		if ncc != uint8(i+1) {
+			t.Errorf("unexpected NCC: %d", ncc)
+		}
+	}
+
+	// Vertical key derivation with NH and extended EARFCN-DL.
+	c = eps.NewNHChain(kasme, kenb)
+	nh, _ := c.Next()
+	if diff := cmp.Diff(eps.KeNBStar(nh, 1, 66436), []byte{
+		0x57, 0xc7, 0xc3, 0xee, 0xc5, 0x00, 0xb0, 0xfd, 0x60, 0x05, 0x6f, 0x96, 0x27, 0x2d, 0xbd, 0x5e,
+		0xab, 0x75, 0x31, 0x92, 0x00, 0x70, 0xc2, 0xd3, 0x60, 0xb4, 0xcd, 0x38, 0x81, 0x38, 0x07, 0x24,
+	}); diff != "" {
+		t.Error(diff)
+	}
+
+	for i := 0; i < 7; i++ {
+		c.Next()
+	}
+	if _, ncc := c.NH(); ncc != 0 {
+		t.Errorf("NCC is expected to wrap around: %d", ncc)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package fivegs provides the key derivation of the 5GS key hierarchy below KSEAF (Annex A,
+TS 33.501), which are KAMF, KgNB, KN3IWF, NH, KNG-RAN*, KAMF' and the algorithm keys for NAS,
+RRC and UP.
+
+KSEAF can be computed with milenage.ComputeKSEAF from KAUSF, which is computed by
+milenage.Milenage.ComputeKAUSF in 5G-AKA or derived from EMSK in EAP-AKA'.
+*/
+package fivegs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/internal/keyderiv"
+)
+
+// FC definitions (Annex A, TS 33.501).
+const (
+	fcAlgorithmKey = 0x69
+	fcKAMF         = 0x6d
+	fcKgNB         = 0x6e
+	fcNH           = 0x6f
+	fcKNGRANStar   = 0x70
+	fcKAMFPrime    = 0x72
+)
+
+// Algorithm type distinguisher definitions (A.8, TS 33.501).
+const (
+	NASEncAlg uint8 = 0x01
+	NASIntAlg uint8 = 0x02
+	RRCEncAlg uint8 = 0x03
+	RRCIntAlg uint8 = 0x04
+	UPEncAlg  uint8 = 0x05
+	UPIntAlg  uint8 = 0x06
+)
+
+// Algorithm identity definitions (5.11.1, TS 33.501).
+const (
+	NEA0 uint8 = 0
+	NEA1 uint8 = 1
+	NEA2 uint8 = 2
+	NEA3 uint8 = 3
+
+	NIA0 uint8 = 0
+	NIA1 uint8 = 1
+	NIA2 uint8 = 2
+	NIA3 uint8 = 3
+)
+
+// Access type distinguisher definitions (A.9, TS 33.501).
+const (
+	AccessType3GPP    uint8 = 0x01
+	AccessTypeNon3GPP uint8 = 0x02
+)
+
+// Direction definitions for KAMF' derivation (A.13, TS 33.501).
+const (
+	DirectionIdleMobility uint8 = 0x00
+	DirectionHandover     uint8 = 0x01
+)
+
+// DefaultABBA is the ABBA parameter value defined in Table A.7.1-1, TS 33.501.
+var DefaultABBA = []byte{0x00, 0x00}
+
+// KAMF derives KAMF from KSEAF, SUPI and ABBA (A.7, TS 33.501).
+//
+// P0 is the IMSI part of SUPI for the IMSI type (A.7.0, TS 33.501), so supi can be given
+// in the SBI form "imsi-<IMSI>" as it is sent from AUSF, or as bare IMSI digits.
+func KAMF(kseaf []byte, supi string, abba []byte) ([]byte, error) {
+	if len(abba) < 2 {
+		return nil, fmt.Errorf("length of ABBA should be at least %d, got: %d", 2, len(abba))
+	}
+	return milenage.KDF(kseaf, fcKAMF, []byte(strings.TrimPrefix(supi, "imsi-")), abba), nil
+}
+
+// KgNB derives KgNB (or KN3IWF with AccessTypeNon3GPP) from KAMF, the uplink NAS COUNT
+// and the access type distinguisher (A.9, TS 33.501).
+func KgNB(kamf []byte, ulNASCount uint32, accessType uint8) []byte {
+	return milenage.KDF(kamf, fcKgNB, binary.BigEndian.AppendUint32(nil, ulNASCount), []byte{accessType})
+}
+
+// KN3IWF derives KN3IWF from KAMF and the uplink NAS COUNT (A.9, TS 33.501).
+func KN3IWF(kamf []byte, ulNASCount uint32) []byte {
+	return KgNB(kamf, ulNASCount, AccessTypeNon3GPP)
+}
+
+// NH derives NH from KAMF and SYNC-input, which is the KgNB derived at the initial
+// context setup or the previous NH (A.10, TS 33.501).
+func NH(kamf, syncInput []byte) []byte {
+	return milenage.KDF(kamf, fcNH, syncInput)
+}
+
+// KNGRANStar derives KNG-RAN* for handover from key, which is either the current KgNB
+// (horizontal derivation) or NH (vertical derivation), with PCI and ARFCN-DL of the target
+// cell (A.11, TS 33.501).
+//
+// ARFCN-DL is encoded in 3 octets.
+func KNGRANStar(key []byte, pci uint16, arfcnDL uint32) []byte {
+	return milenage.KDF(key, fcKNGRANStar,
+		binary.BigEndian.AppendUint16(nil, pci),
+		binary.BigEndian.AppendUint32(nil, arfcnDL)[1:],
+	)
+}
+
+// KAMFPrime derives KAMF' from KAMF for the horizontal derivation on mobility, with
+// the direction and the NAS COUNT, which is the uplink NAS COUNT for idle mobility and
+// the downlink NAS COUNT for handover (A.13, TS 33.501).
+func KAMFPrime(kamf []byte, direction uint8, count uint32) []byte {
+	return milenage.KDF(kamf, fcKAMFPrime, []byte{direction}, binary.BigEndian.AppendUint32(nil, count))
+}
+
+// AlgorithmKey derives the 128-bit key for the algorithm identified by the algorithm type
+// distinguisher and the algorithm identity from key, which is KAMF for NAS and KgNB for
+// RRC and UP (A.8, TS 33.501). The 128 least significant bits of the KDF output are used.
+func AlgorithmKey(key []byte, distinguisher, algID uint8) []byte {
+	return keyderiv.AlgorithmKey(key, fcAlgorithmKey, distinguisher, algID)
+}
+
+// KNASenc derives KNASenc from KAMF for the NEA algorithm identity.
+func KNASenc(kamf []byte, nea uint8) []byte {
+	return AlgorithmKey(kamf, NASEncAlg, nea)
+}
+
+// KNASint derives KNASint from KAMF for the NIA algorithm identity.
+func KNASint(kamf []byte, nia uint8) []byte {
+	return AlgorithmKey(kamf, NASIntAlg, nia)
+}
+
+// KRRCenc derives KRRCenc from KgNB for the NEA algorithm identity.
+func KRRCenc(kgnb []byte, nea uint8) []byte {
+	return AlgorithmKey(kgnb, RRCEncAlg, nea)
+}
+
+// KRRCint derives KRRCint from KgNB for the NIA algorithm identity.
+func KRRCint(kgnb []byte, nia uint8) []byte {
+	return AlgorithmKey(kgnb, RRCIntAlg, nia)
+}
+
+// KUPenc derives KUPenc from KgNB for the NEA algorithm identity.
+func KUPenc(kgnb []byte, nea uint8) []byte {
+	return AlgorithmKey(kgnb, UPEncAlg, nea)
+}
+
+// KUPint derives KUPint from KgNB for the NIA algorithm identity.
+func KUPint(kgnb []byte, nia uint8) []byte {
+	return AlgorithmKey(kgnb, UPIntAlg, nia)
+}
+
+// NHChain keeps the NH and NCC pair for handover (6.9.2.1.1, TS 33.501).
+//
+// NHChain is not safe for concurrent use.
+type NHChain struct {
+	chain *keyderiv.NHChain
+}
+
+// NewNHChain creates a new NHChain at the initial context setup, where the initial
+// KgNB is derived from KAMF and NCC is zero.
+func NewNHChain(kamf, kgnb []byte) *NHChain {
+	return &NHChain{chain: keyderiv.NewNHChain(fcNH, kamf, kgnb)}
+}
+
+// NH returns the current NH and NCC. NH is the initial KgNB while NCC is zero.
+func (c *NHChain) NH() (nh []byte, ncc uint8) {
+	return c.chain.NH()
+}
+
+// Next derives the next NH from KAMF and the current NH as NH does, and returns it
+// with NCC incremented. The NCC wraps around in the same way as in EPS.
+func (c *NHChain) Next() (nh []byte, ncc uint8) {
+	return c.chain.Next()
+}
@@ -0,0 +1,102 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package fivegs_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/fivegs"
+)
+
+func computeKAMF(t *testing.T) []byte {
+	t.Helper()
+
+	b := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	m := milenage.New(b, b, b, 1, 0x8000)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+	kausf, err := m.ComputeKAUSF("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kseaf, err := milenage.ComputeKSEAF(kausf, "001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kamf, err := fivegs.KAMF(kseaf, "imsi-001010000000001", fivegs.DefaultABBA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kamf
+}
+
+// The expected values are not from TS 33.501, which has test data only for SUCI in Annex C,
+// but generated with this package from the dummy values in computeKAMF.
+func TestKeys(t *testing.T) {
+	kamf := computeKAMF(t)
+	kgnb := fivegs.KgNB(kamf, 0, fivegs.AccessType3GPP)
+
+	for _, c := range []struct {
+		description string
+		got         []byte
+		want        string
+	}{
+		{"KAMF", kamf, "7e571dd68a22c31c404a6ebe7a9e6526220b1ac02ada2e7d32c222ff4a52b21d"},
+		{"KgNB", kgnb, "2926575e5f1ef165062aeb1583f1598a14891bd28c6ffe9894490fb446375ba5"},
+		{"KN3IWF", fivegs.KN3IWF(kamf, 0), "1c3095ae27b2b2531eafb1d306d477ffb5205a829cd3a542bec23ede0b25ea50"},
+		{"KNASenc", fivegs.KNASenc(kamf, fivegs.NEA2), "504889e1e47853d8dad6ba1076994461"},
+		{"KNASint", fivegs.KNASint(kamf, fivegs.NIA2), "2fb2590c903ede2312cc1ed7b9394b12"},
+		{"KRRCenc", fivegs.KRRCenc(kgnb, fivegs.NEA2), "3aeca9ed844c23a11cd5d03ff4531df0"},
+		{"KRRCint", fivegs.KRRCint(kgnb, fivegs.NIA2), "28a86119663e1ce7947fc4f33ff84fd5"},
+		{"KUPenc", fivegs.KUPenc(kgnb, fivegs.NEA2), "9fa77737e6a6987111fc815a6d20230b"},
+		{"KUPint", fivegs.KUPint(kgnb, fivegs.NIA2), "943acb18e4db324f01f72969977fdf98"},
+		{"KAMF'", fivegs.KAMFPrime(kamf, fivegs.DirectionHandover, 5), "d787bab11069bbd5e50174cc6f37ac14765fa5cf390068209d905cd56ea125bb"},
+	} {
+		if diff := cmp.Diff(hex.EncodeToString(c.got), c.want); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+	}
+
+	if _, err := fivegs.KAMF(kamf, "imsi-001010000000001", nil); err == nil {
+		t.Error("expected error with invalid ABBA")
+	}
+}
+
+func TestNHChain(t *testing.T) {
+	kamf := computeKAMF(t)
+	kgnb := fivegs.KgNB(kamf, 0, fivegs.AccessType3GPP)
+	c := fivegs.NewNHChain(kamf, kgnb)
+
+	if nh, ncc := c.NH(); ncc != 0 || !cmp.Equal(nh, kgnb) {
+		t.Errorf("unexpected initial NH and NCC: %x, %d", nh, ncc)
+	}
+
+	for i, want := range []string{
+		"1a4fbc11a96d3f3d9fc860a7dd01d6d00656b55fea7d7a24fdecf5092110e015",
+		"d22dbc9a8a05ec9877f2d6f45bed15c4155da8977003986a50e365a0f68fc6eb",
+	} {
+		nh, ncc := c.Next()
+		if diff := cmp.Diff(hex.EncodeToString(nh), want); diff != "" {
+			t.Errorf("NH%d failed: \n%s", i+1, diff)
+		}
+		if ncc != uint8(i+1) {
+			t.Errorf("unexpected NCC: %d", ncc)
+		}
+	}
+
+	// Vertical key derivation with NH.
+	c = fivegs.NewNHChain(kamf, kgnb)
+	nh, _ := c.Next()
+	if diff := cmp.Diff(fivegs.KNGRANStar(nh, 1, 632628), []byte{
+		0xc6, 0x13, 0xd7, 0x95, 0xb8, 0x7e, 0x11, 0x6f, 0xee, 0xe6, 0xdf, 0x39, 0x59, 0xf8, 0xd1, 0xf0,
+		0xe0, 0x1c, 0x76, 0x8d, 0x3a, 0xfa, 0x0b, 0x07, 0x5d, 0xda, 0xe2, 0x29, 0xeb, 0xc0, 0x8a, 0x15,
+	}); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package gba provides the key derivation of Generic Bootstrapping Architecture (TS 33.220),
+which are Ks, B-TID and the NAF specific keys Ks_(ext)_NAF and Ks_int_NAF, on top of the
+values computed by the milenage package.
+*/
+package gba
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/wmnsk/milenage"
+)
+
+// fcNAF is the FC used to derive the NAF specific keys (B.3, TS 33.220).
+const fcNAF = 0x01
+
+// Ks returns Ks = CK || IK (4.5.2, TS 33.220).
+func Ks(ck, ik []byte) []byte {
+	ks := make([]byte, 0, len(ck)+len(ik))
+	return append(append(ks, ck...), ik...)
+}
+
+// BTID builds B-TID as base64(RAND)@BSF_servers_domain_name in NAI format (4.5.2, TS 33.220).
+func BTID(rand []byte, bsfHost string) string {
+	return base64.StdEncoding.EncodeToString(rand) + "@" + bsfHost
+}
+
+// ParseBTID retrieves RAND and the BSF server's domain name from B-TID.
+func ParseBTID(btid string) (rand []byte, bsfHost string, err error) {
+	r, host, ok := strings.Cut(btid, "@")
+	if !ok || host == "" {
+		return nil, "", fmt.Errorf("invalid B-TID: %s", btid)
+	}
+	if rand, err = base64.StdEncoding.DecodeString(r); err != nil {
+		return nil, "", fmt.Errorf("invalid RAND in B-TID: %w", err)
+	}
+	return rand, host, nil
+}
+
+// NAFID builds NAF_Id as the FQDN of NAF || Ua security protocol identifier (4.5.2, TS 33.220).
+func NAFID(fqdn string, uaSecurityProtocolID []byte) ([]byte, error) {
+	if len(uaSecurityProtocolID) != 5 {
+		return nil, fmt.Errorf("length of Ua security protocol identifier should be %d, got: %d", 5, len(uaSecurityProtocolID))
+	}
+	return append([]byte(fqdn), uaSecurityProtocolID...), nil
+}
+
+// KsNAF derives Ks_NAF for GBA_ME, which is also Ks_ext_NAF for GBA_U, as
+// KDF(Ks, "gba-me", RAND, IMPI, NAF_Id) (4.5.2 and B.3, TS 33.220).
+func KsNAF(ks, rand []byte, impi string, nafID []byte) []byte {
+	return milenage.KDF(ks, fcNAF, []byte("gba-me"), rand, []byte(impi), nafID)
+}
+
+// KsExtNAF derives Ks_ext_NAF for GBA_U, which is the same as KsNAF (5.3.2, TS 33.220).
+func KsExtNAF(ks, rand []byte, impi string, nafID []byte) []byte {
+	return KsNAF(ks, rand, impi, nafID)
+}
+
+// KsIntNAF derives Ks_int_NAF for GBA_U as KDF(Ks, "gba-u", RAND, IMPI, NAF_Id)
+// (5.3.2 and B.3, TS 33.220).
+func KsIntNAF(ks, rand []byte, impi string, nafID []byte) []byte {
+	return milenage.KDF(ks, fcNAF, []byte("gba-u"), rand, []byte(impi), nafID)
+}
+
+// Bootstrapping is the bootstrapping context shared by BSF and UE after a successful
+// bootstrapping procedure over Ub.
+type Bootstrapping struct {
+	BTID string
+	IMPI string
+	RAND []byte
+	Ks   []byte
+}
+
+// NewBootstrapping creates a new Bootstrapping from the values in m.
+//
+// Note that this function should be called after F2345 is done (to generate CK and IK).
+func NewBootstrapping(m *milenage.Milenage, impi, bsfHost string) (*Bootstrapping, error) {
+	if len(m.RAND) != 16 || len(m.CK) != 16 || len(m.IK) != 16 {
+		return nil, fmt.Errorf("invalid length of RAND, CK or IK: %d, %d, %d", len(m.RAND), len(m.CK), len(m.IK))
+	}
+	return &Bootstrapping{
+		BTID: BTID(m.RAND, bsfHost),
+		IMPI: impi,
+		RAND: append([]byte{}, m.RAND...),
+		Ks:   Ks(m.CK, m.IK),
+	}, nil
+}
+
+// KsNAF derives Ks_NAF (or Ks_ext_NAF) for the NAF identified by nafID.
+func (b *Bootstrapping) KsNAF(nafID []byte) []byte {
+	return KsNAF(b.Ks, b.RAND, b.IMPI, nafID)
+}
+
+// KsIntNAF derives Ks_int_NAF for the NAF identified by nafID.
+func (b *Bootstrapping) KsIntNAF(nafID []byte) []byte {
+	return KsIntNAF(b.Ks, b.RAND, b.IMPI, nafID)
+}
@@ -0,0 +1,89 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gba_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/gba"
+)
+
+const impi = "001010000000001@ims.mnc001.mcc001.3gppnetwork.org"
+
+func TestBootstrapping(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if _, _, _, _, err := m.F2345(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := gba.NewBootstrapping(m, impi, "bsf.mnc001.mcc001.pub.3gppnetwork.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ks is CK || IK of Test Set 1 in TS 35.208, and B-TID is its RAND in base64.
+	// Ks_NAF below are self-generated with this package, as TS 33.220 has no test data.
+	if b.BTID != "I1U8vpY3qJ0hiuZNrke/NQ==@bsf.mnc001.mcc001.pub.3gppnetwork.org" {
+		t.Errorf("unexpected B-TID: %s", b.BTID)
+	}
+	if diff := cmp.Diff(b.Ks, []byte{
+		0xb4, 0x0b, 0xa9, 0xa3, 0xc5, 0x8b, 0x2a, 0x05, 0xbb, 0xf0, 0xd9, 0x87, 0xb2, 0x1b, 0xf8, 0xcb,
+		0xf7, 0x69, 0xbc, 0xd7, 0x51, 0x04, 0x46, 0x04, 0x12, 0x76, 0x72, 0x71, 0x1c, 0x6d, 0x34, 0x41,
+	}); diff != "" {
+		t.Error(diff)
+	}
+
+	rand, host, err := gba.ParseBTID(b.BTID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(rand, m.RAND); diff != "" {
+		t.Error(diff)
+	}
+	if host != "bsf.mnc001.mcc001.pub.3gppnetwork.org" {
+		t.Errorf("unexpected host: %s", host)
+	}
+
+	nafID, err := gba.NAFID("naf.example.com", []byte{0x01, 0x00, 0x00, 0x00, 0x02})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(b.KsNAF(nafID), []byte{
+		0xf2, 0x65, 0xd2, 0x91, 0x89, 0x60, 0x3e, 0xd3, 0xd4, 0xb2, 0x75, 0xb8, 0xdd, 0x60, 0xa9, 0xd0,
+		0x06, 0x4a, 0x83, 0x94, 0x29, 0x9c, 0x73, 0x02, 0x5b, 0xc3, 0x49, 0xa5, 0xc9, 0xa8, 0x6a, 0xd0,
+	}); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(gba.KsExtNAF(b.Ks, b.RAND, impi, nafID), b.KsNAF(nafID)); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(b.KsIntNAF(nafID), []byte{
+		0xf0, 0x04, 0x80, 0x88, 0x1c, 0x74, 0xc5, 0x09, 0x65, 0x4a, 0x24, 0x6c, 0x33, 0x03, 0x29, 0xf1,
+		0x28, 0x7a, 0x0d, 0x37, 0x35, 0x99, 0x4e, 0x77, 0x88, 0x69, 0xa7, 0xbc, 0x83, 0xb9, 0xc3, 0x15,
+	}); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestError(t *testing.T) {
+	for _, btid := range []string{"I1U8vpY3qJ0hiuZNrke/NQ==", "I1U8vpY3qJ0hiuZNrke/NQ==@", "!!!@bsf.example.com"} {
+		if _, _, err := gba.ParseBTID(btid); err == nil {
+			t.Errorf("expected error with %s", btid)
+		}
+	}
+	if _, err := gba.NAFID("naf.example.com", []byte{0x01}); err == nil {
+		t.Error("expected error with invalid Ua security protocol identifier")
+	}
+	if _, err := gba.NewBootstrapping(&milenage.Milenage{}, impi, "bsf.example.com"); err == nil {
+		t.Error("expected error without CK and IK")
+	}
+}
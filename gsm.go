@@ -0,0 +1,51 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import "fmt"
+
+// C2 is the conversion function c2 defined in 6.8.1.2, TS 33.102, which converts
+// XRES (or RES) into SRES used in GSM authentication.
+//
+// XRES is padded with zeros to 128 bits, and the four 32-bit blocks are XORed together.
+func C2(xres []byte) []byte {
+	sres := make([]byte, 4)
+	for i, b := range xres {
+		sres[i%4] ^= b
+	}
+	return sres
+}
+
+// C3 is the conversion function c3 defined in 6.8.1.2, TS 33.102, which converts
+// CK and IK into the 64-bit GSM cipher key Kc. CK and IK should be 16 bytes each.
+func C3(ck, ik []byte) ([]byte, error) {
+	if len(ck) != 16 {
+		return nil, fmt.Errorf("length of CK should be %d, got: %d", 16, len(ck))
+	}
+	if len(ik) != 16 {
+		return nil, fmt.Errorf("length of IK should be %d, got: %d", 16, len(ik))
+	}
+
+	kc := make([]byte, 8)
+	for i := range kc {
+		kc[i] = ck[i] ^ ck[i+8] ^ ik[i] ^ ik[i+8]
+	}
+	return kc, nil
+}
+
+// GenerateTriplet generates the GSM authentication triplet (RAND, SRES, Kc) from
+// the current values in Milenage, using the conversion functions c2 and c3.
+//
+// Note that this function should be called after F2345 is done (to generate RES, CK and IK).
+func (m *Milenage) GenerateTriplet() (sres, kc []byte, err error) {
+	if err := m.validateLength(); err != nil {
+		return nil, nil, err
+	}
+	kc, err = C3(m.CK, m.IK)
+	if err != nil {
+		return nil, nil, err
+	}
+	return C2(m.RES), kc, nil
+}
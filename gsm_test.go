@@ -0,0 +1,46 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+)
+
+func TestGenerateTriplet(t *testing.T) {
+	c := cases[0]
+	if err := c.input.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	sres, kc, err := c.input.GenerateTriplet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(sres, mustDecodeHex(t, "7b22f5a9")); diff != "" {
+		t.Errorf("%s failed: \n%s", c.description+"/SRES", diff)
+	}
+	if diff := cmp.Diff(kc, mustDecodeHex(t, "9ea12d6ee44cb943")); diff != "" {
+		t.Errorf("%s failed: \n%s", c.description+"/Kc", diff)
+	}
+}
+
+func TestC3Error(t *testing.T) {
+	ck := make([]byte, 16)
+	for _, c := range []struct {
+		description string
+		ck, ik      []byte
+	}{
+		{"short CK", ck[:8], ck},
+		{"short IK", ck, ck[:15]},
+		{"nil IK", ck, nil},
+	} {
+		if _, err := milenage.C3(c.ck, c.ik); err == nil {
+			t.Errorf("%s: expected error", c.description)
+		}
+	}
+}
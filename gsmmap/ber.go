@@ -0,0 +1,108 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gsmmap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// tlv is a BER encoded element with a single octet identifier.
+type tlv struct {
+	tag   byte
+	value []byte
+}
+
+// appendTLV appends the element with tag and value to b, in definite length form.
+func appendTLV(b []byte, tag byte, value []byte) []byte {
+	b = append(b, tag)
+	switch l := len(value); {
+	case l < 0x80:
+		b = append(b, byte(l))
+	case l <= 0xff:
+		b = append(b, 0x81, byte(l))
+	default:
+		b = append(b, 0x82, byte(l>>8), byte(l))
+	}
+	return append(b, value...)
+}
+
+// parseTLV parses the first element in b and returns it with the rest of b.
+// Only the definite length form up to 2 length octets is supported.
+func parseTLV(b []byte) (*tlv, []byte, error) {
+	if len(b) < 2 {
+		return nil, nil, errors.New("too short for BER element")
+	}
+	if b[0]&0x1f == 0x1f {
+		return nil, nil, fmt.Errorf("multi-octet identifier is not supported: %#x", b[0])
+	}
+
+	t := &tlv{tag: b[0]}
+	l, off := int(b[1]), 2
+	switch {
+	case l == 0x80:
+		return nil, nil, errors.New("indefinite length form is not supported")
+	case l > 0x82:
+		return nil, nil, fmt.Errorf("unsupported length form: %#x", l)
+	case l > 0x80:
+		n := l & 0x7f
+		if len(b) < 2+n {
+			return nil, nil, errors.New("too short for BER length")
+		}
+		l = 0
+		for _, c := range b[2 : 2+n] {
+			l = l<<8 | int(c)
+		}
+		off += n
+	}
+	if len(b) < off+l {
+		return nil, nil, fmt.Errorf("too short for BER element with tag %#x: %d", t.tag, len(b))
+	}
+	t.value = b[off : off+l]
+	return t, b[off+l:], nil
+}
+
+// parseTLVs parses all the elements in b, e.g., the contents of a SEQUENCE.
+func parseTLVs(b []byte) ([]*tlv, error) {
+	var ts []*tlv
+	for len(b) > 0 {
+		t, rest, err := parseTLV(b)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+		b = rest
+	}
+	return ts, nil
+}
+
+// parseSequence parses b as an element with tag and returns the elements inside.
+func parseSequence(b []byte, tag byte) ([]*tlv, error) {
+	t, rest, err := parseTLV(b)
+	if err != nil {
+		return nil, err
+	}
+	if t.tag != tag {
+		return nil, fmt.Errorf("unexpected tag: %#x", t.tag)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing bytes after element: %d", len(rest))
+	}
+	return parseTLVs(t.value)
+}
+
+// octetStrings decodes the elements in ts as OCTET STRING into dst in order.
+func octetStrings(ts []*tlv, dst ...*[]byte) error {
+	if len(ts) < len(dst) {
+		return fmt.Errorf("too few elements: %d", len(ts))
+	}
+	for i, d := range dst {
+		if ts[i].tag != tagOctetString {
+			return fmt.Errorf("unexpected tag: %#x", ts[i].tag)
+		}
+		*d = append([]byte{}, ts[i].value...)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package gsmmap provides the BER encoding and decoding of MAP SendAuthenticationInfoArg and
+SendAuthenticationInfoRes (version 3, TS 29.002), to serialise the quintets and triplets
+computed by the milenage package for a HLR stand-in.
+
+Only the parameters of the operation are handled here, and TCAP and the components are out of
+the scope of this package. extensionContainer and the other optional elements not defined
+in this package are ignored on decoding.
+*/
+package gsmmap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wmnsk/milenage"
+)
+
+// tag definitions.
+const (
+	tagInteger     byte = 0x02
+	tagOctetString byte = 0x04
+	tagNull        byte = 0x05
+	tagSequence    byte = 0x30
+
+	// SendAuthenticationInfoArg.
+	tagIMSI                       byte = 0x80
+	tagImmediateResponsePreferred byte = 0x81
+
+	// SendAuthenticationInfoRes.
+	tagSendAuthenticationInfoRes byte = 0xa3
+	tagTripletList               byte = 0xa0
+	tagQuintupletList            byte = 0xa1
+)
+
+// SendAuthenticationInfoArg is the argument of MAP SendAuthenticationInfo operation.
+type SendAuthenticationInfoArg struct {
+	// IMSI is the IMSI in digits.
+	IMSI string
+	// NumberOfRequestedVectors is the number of vectors requested, from 1 to 5.
+	NumberOfRequestedVectors int
+	// SegmentationProhibited and ImmediateResponsePreferred are true if
+	// the corresponding NULL elements are present.
+	SegmentationProhibited     bool
+	ImmediateResponsePreferred bool
+	ResynchronisationInfo      *ResynchronisationInfo
+}
+
+// ResynchronisationInfo is the Re-synchronisationInfo, which is RAND and AUTS.
+// RAND and AUTS can be used with milenage.Milenage.VerifyAUTS to re-synchronise SQN.
+type ResynchronisationInfo struct {
+	RAND []byte
+	AUTS []byte
+}
+
+// Marshal returns the byte sequence of a in BER.
+func (a *SendAuthenticationInfoArg) Marshal() ([]byte, error) {
+	imsi, err := encodeTBCD(a.IMSI)
+	if err != nil {
+		return nil, err
+	}
+	if a.NumberOfRequestedVectors < 1 || a.NumberOfRequestedVectors > 5 {
+		return nil, fmt.Errorf("invalid numberOfRequestedVectors: %d", a.NumberOfRequestedVectors)
+	}
+
+	b := appendTLV(nil, tagIMSI, imsi)
+	b = appendTLV(b, tagInteger, []byte{byte(a.NumberOfRequestedVectors)})
+	if a.SegmentationProhibited {
+		b = appendTLV(b, tagNull, nil)
+	}
+	if a.ImmediateResponsePreferred {
+		b = appendTLV(b, tagImmediateResponsePreferred, nil)
+	}
+	if r := a.ResynchronisationInfo; r != nil {
+		if len(r.RAND) != 16 || len(r.AUTS) != 14 {
+			return nil, fmt.Errorf("invalid length of RAND or AUTS: %d, %d", len(r.RAND), len(r.AUTS))
+		}
+		b = appendTLV(b, tagSequence, appendTLV(appendTLV(nil, tagOctetString, r.RAND), tagOctetString, r.AUTS))
+	}
+	return appendTLV(nil, tagSequence, b), nil
+}
+
+// ParseSendAuthenticationInfoArg decodes SendAuthenticationInfoArg in b.
+func ParseSendAuthenticationInfoArg(b []byte) (*SendAuthenticationInfoArg, error) {
+	ts, err := parseSequence(b, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &SendAuthenticationInfoArg{}
+	for _, t := range ts {
+		switch t.tag {
+		case tagIMSI:
+			if a.IMSI, err = decodeTBCD(t.value); err != nil {
+				return nil, err
+			}
+		case tagInteger:
+			if len(t.value) != 1 {
+				return nil, fmt.Errorf("invalid numberOfRequestedVectors: %x", t.value)
+			}
+			a.NumberOfRequestedVectors = int(t.value[0])
+		case tagNull:
+			a.SegmentationProhibited = true
+		case tagImmediateResponsePreferred:
+			a.ImmediateResponsePreferred = true
+		case tagSequence:
+			rs, err := parseTLVs(t.value)
+			if err != nil {
+				return nil, err
+			}
+			r := &ResynchronisationInfo{}
+			if err := octetStrings(rs, &r.RAND, &r.AUTS); err != nil {
+				return nil, fmt.Errorf("invalid re-synchronisationInfo: %w", err)
+			}
+			a.ResynchronisationInfo = r
+		}
+	}
+	if a.IMSI == "" {
+		return nil, errors.New("missing imsi")
+	}
+	return a, nil
+}
+
+// AuthenticationTriplet is the AuthenticationTriplet (RAND, SRES, Kc) for GSM.
+type AuthenticationTriplet struct {
+	RAND []byte
+	SRES []byte
+	Kc   []byte
+}
+
+// NewAuthenticationTriplet creates a new AuthenticationTriplet from the values in m,
+// converted with the functions c2 and c3.
+//
+// Note that this function should be called after F2345 is done.
+func NewAuthenticationTriplet(m *milenage.Milenage) (*AuthenticationTriplet, error) {
+	sres, kc, err := m.GenerateTriplet()
+	if err != nil {
+		return nil, err
+	}
+	return &AuthenticationTriplet{RAND: append([]byte{}, m.RAND...), SRES: sres, Kc: kc}, nil
+}
+
+// AuthenticationQuintuplet is the AuthenticationQuintuplet (RAND, XRES, CK, IK, AUTN) for UMTS.
+type AuthenticationQuintuplet struct {
+	RAND []byte
+	XRES []byte
+	CK   []byte
+	IK   []byte
+	AUTN []byte
+}
+
+// NewAuthenticationQuintuplet creates a new AuthenticationQuintuplet from the values in m.
+//
+// Note that this function should be called after F1 and F2345 are done.
+func NewAuthenticationQuintuplet(m *milenage.Milenage) (*AuthenticationQuintuplet, error) {
+	autn, err := m.GenerateAUTN()
+	if err != nil {
+		return nil, err
+	}
+	return &AuthenticationQuintuplet{
+		RAND: append([]byte{}, m.RAND...),
+		XRES: append([]byte{}, m.RES...),
+		CK:   append([]byte{}, m.CK...),
+		IK:   append([]byte{}, m.IK...),
+		AUTN: autn,
+	}, nil
+}
+
+// SendAuthenticationInfoRes is the result of MAP SendAuthenticationInfo operation.
+//
+// AuthenticationSetList is CHOICE of TripletList and QuintupletList, and only one of them
+// can be set. Both can be empty if the HLR has no vector to return.
+type SendAuthenticationInfoRes struct {
+	TripletList    []*AuthenticationTriplet
+	QuintupletList []*AuthenticationQuintuplet
+}
+
+// Marshal returns the byte sequence of r in BER.
+func (r *SendAuthenticationInfoRes) Marshal() ([]byte, error) {
+	if len(r.TripletList) > 0 && len(r.QuintupletList) > 0 {
+		return nil, errors.New("only one of TripletList and QuintupletList can be set")
+	}
+	if len(r.TripletList) > 5 || len(r.QuintupletList) > 5 {
+		return nil, errors.New("too many vectors in AuthenticationSetList")
+	}
+
+	var b []byte
+	if len(r.TripletList) > 0 {
+		var l []byte
+		for _, t := range r.TripletList {
+			l = appendTLV(l, tagSequence, appendOctetStrings(t.RAND, t.SRES, t.Kc))
+		}
+		b = appendTLV(b, tagTripletList, l)
+	}
+	if len(r.QuintupletList) > 0 {
+		var l []byte
+		for _, q := range r.QuintupletList {
+			l = appendTLV(l, tagSequence, appendOctetStrings(q.RAND, q.XRES, q.CK, q.IK, q.AUTN))
+		}
+		b = appendTLV(b, tagQuintupletList, l)
+	}
+	return appendTLV(nil, tagSendAuthenticationInfoRes, b), nil
+}
+
+// ParseSendAuthenticationInfoRes decodes SendAuthenticationInfoRes in b.
+func ParseSendAuthenticationInfoRes(b []byte) (*SendAuthenticationInfoRes, error) {
+	ts, err := parseSequence(b, tagSendAuthenticationInfoRes)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &SendAuthenticationInfoRes{}
+	for _, t := range ts {
+		if t.tag != tagTripletList && t.tag != tagQuintupletList {
+			continue
+		}
+		vs, err := parseTLVs(t.value)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vs {
+			if v.tag != tagSequence {
+				return nil, fmt.Errorf("unexpected tag in AuthenticationSetList: %#x", v.tag)
+			}
+			es, err := parseTLVs(v.value)
+			if err != nil {
+				return nil, err
+			}
+
+			if t.tag == tagTripletList {
+				tr := &AuthenticationTriplet{}
+				if err := octetStrings(es, &tr.RAND, &tr.SRES, &tr.Kc); err != nil {
+					return nil, fmt.Errorf("invalid AuthenticationTriplet: %w", err)
+				}
+				r.TripletList = append(r.TripletList, tr)
+				continue
+			}
+			q := &AuthenticationQuintuplet{}
+			if err := octetStrings(es, &q.RAND, &q.XRES, &q.CK, &q.IK, &q.AUTN); err != nil {
+				return nil, fmt.Errorf("invalid AuthenticationQuintuplet: %w", err)
+			}
+			r.QuintupletList = append(r.QuintupletList, q)
+		}
+	}
+	return r, nil
+}
+
+func appendOctetStrings(values ...[]byte) []byte {
+	var b []byte
+	for _, v := range values {
+		b = appendTLV(b, tagOctetString, v)
+	}
+	return b
+}
+
+// encodeTBCD encodes IMSI digits into TBCD-STRING, with the filler 0xF if the number
+// of digits is odd.
+func encodeTBCD(imsi string) ([]byte, error) {
+	if l := len(imsi); l < 5 || l > 15 {
+		return nil, fmt.Errorf("invalid length of IMSI: %d", l)
+	}
+
+	b := make([]byte, (len(imsi)+1)/2)
+	for i := range b {
+		b[i] = 0xf0
+	}
+	for i, c := range imsi {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("invalid IMSI: %s", imsi)
+		}
+		d := byte(c - '0')
+		if i%2 == 0 {
+			b[i/2] = b[i/2]&0xf0 | d
+		} else {
+			b[i/2] = b[i/2]&0x0f | d<<4
+		}
+	}
+	return b, nil
+}
+
+// decodeTBCD decodes TBCD-STRING into digits.
+func decodeTBCD(b []byte) (string, error) {
+	var sb strings.Builder
+	for i, c := range b {
+		for j, d := range []byte{c & 0x0f, c >> 4} {
+			if d == 0x0f && i == len(b)-1 && j == 1 {
+				break
+			}
+			if d > 9 {
+				return "", fmt.Errorf("invalid TBCD-STRING: %x", b)
+			}
+			sb.WriteByte('0' + d)
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package gsmmap_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/gsmmap"
+)
+
+func TestSendAuthenticationInfoArg(t *testing.T) {
+	a := &gsmmap.SendAuthenticationInfoArg{
+		IMSI:                       "001010000000001",
+		NumberOfRequestedVectors:   2,
+		ImmediateResponsePreferred: true,
+	}
+	b, err := a.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(b, []byte{
+		0x30, 0x0f, 0x80, 0x08, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0xf1, 0x02, 0x01, 0x02, 0x81,
+		0x00,
+	}); diff != "" {
+		t.Error(diff)
+	}
+
+	got, err := gsmmap.ParseSendAuthenticationInfoArg(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, a); diff != "" {
+		t.Error(diff)
+	}
+
+	// the shortest IMSI (MCC and 2-digit MNC only) is accepted.
+	short := &gsmmap.SendAuthenticationInfoArg{IMSI: "00101", NumberOfRequestedVectors: 1}
+	b, err = short.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = gsmmap.ParseSendAuthenticationInfoArg(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, short); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, a := range []*gsmmap.SendAuthenticationInfoArg{
+		{IMSI: "0010", NumberOfRequestedVectors: 1},
+		{IMSI: "0010100000000001", NumberOfRequestedVectors: 1},
+		{IMSI: "00101000000000a", NumberOfRequestedVectors: 1},
+		{IMSI: "001010000000001", NumberOfRequestedVectors: 6},
+	} {
+		if _, err := a.Marshal(); err == nil {
+			t.Errorf("expected error with %+v", a)
+		}
+	}
+}
+
+func TestResynchronisationInfo(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// UE has SQNMS=0x1000 and rejects the AUTN received.
+	ue := milenage.NewWithOPc(m.K, m.OPc, m.RAND, 0x1000, 0)
+	auts, err := ue.GenerateAUTS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &gsmmap.SendAuthenticationInfoArg{
+		IMSI:                     "00101123456789",
+		NumberOfRequestedVectors: 1,
+		SegmentationProhibited:   true,
+		ResynchronisationInfo:    &gsmmap.ResynchronisationInfo{RAND: m.RAND, AUTS: auts},
+	}
+	b, err := a.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := gsmmap.ParseSendAuthenticationInfoArg(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, a); diff != "" {
+		t.Error(diff)
+	}
+
+	r := got.ResynchronisationInfo
+	hlr := milenage.NewWithOPc(m.K, m.OPc, r.RAND, 0, 0)
+	sqnMS, ok, err := hlr.VerifyAUTS(r.AUTS)
+	if err != nil || !ok {
+		t.Fatalf("AUTS verification failed: %v, %v", ok, err)
+	}
+	if sqn := milenage.SQNToUint64([6]byte(sqnMS)); sqn != 0x1000 {
+		t.Errorf("unexpected SQNMS: %x", sqn)
+	}
+}
+
+func TestSendAuthenticationInfoRes(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := gsmmap.NewAuthenticationQuintuplet(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := gsmmap.NewAuthenticationTriplet(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sres, kc, err := m.GenerateTriplet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(tr, &gsmmap.AuthenticationTriplet{RAND: m.RAND, SRES: sres, Kc: kc}); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, c := range []struct {
+		description string
+		res         *gsmmap.SendAuthenticationInfoRes
+		header      []byte
+	}{
+		{
+			"quintuplets",
+			&gsmmap.SendAuthenticationInfoRes{QuintupletList: []*gsmmap.AuthenticationQuintuplet{q, q}},
+			// [3] { [1] { SEQUENCE (82 octets) x2 } }.
+			[]byte{0xa3, 0x81, 0xab, 0xa1, 0x81, 0xa8, 0x30, 0x52},
+		}, {
+			"triplets",
+			&gsmmap.SendAuthenticationInfoRes{TripletList: []*gsmmap.AuthenticationTriplet{tr}},
+			[]byte{0xa3, 0x26, 0xa0, 0x24, 0x30, 0x22},
+		}, {
+			"empty",
+			&gsmmap.SendAuthenticationInfoRes{},
+			[]byte{0xa3, 0x00},
+		},
+	} {
+		b, err := c.res.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(b[:len(c.header)], c.header); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+
+		got, err := gsmmap.ParseSendAuthenticationInfoRes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, c.res); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+	}
+
+	res := &gsmmap.SendAuthenticationInfoRes{
+		TripletList:    []*gsmmap.AuthenticationTriplet{tr},
+		QuintupletList: []*gsmmap.AuthenticationQuintuplet{q},
+	}
+	if _, err := res.Marshal(); err == nil {
+		t.Error("expected error with both triplets and quintuplets")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, c := range []struct {
+		description string
+		b           []byte
+	}{
+		{"indefinite length", []byte{0x30, 0x80, 0x80, 0x08, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0xf1, 0x00, 0x00, 0x00, 0x00}},
+		{"too short", []byte{0x30, 0x0f, 0x80, 0x08, 0x00, 0x01, 0x01}},
+		{"missing imsi", []byte{0x30, 0x03, 0x02, 0x01, 0x01}},
+		{"invalid TBCD", []byte{0x30, 0x0d, 0x80, 0x08, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0xa0, 0xf1, 0x02, 0x01, 0x01}},
+	} {
+		if _, err := gsmmap.ParseSendAuthenticationInfoArg(c.b); err == nil {
+			t.Errorf("%s: expected error", c.description)
+		}
+	}
+
+	if _, err := gsmmap.ParseSendAuthenticationInfoRes([]byte{0xa3, 0x05, 0xa1, 0x03, 0x30, 0x01, 0x04}); err == nil {
+		t.Error("expected error with invalid quintuplet")
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package imsipsec provides the derivation of the ESP keys from CK and IK for the IMS security
+agreement (Annex I, TS 33.203), and the parsing and formatting of Security-Client,
+Security-Server and Security-Verify headers (RFC 3329 and Annex H, TS 33.203) to set up
+the IPsec security associations between UE and P-CSCF after an IMS AKA exchange.
+*/
+package imsipsec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MechanismIPsec3GPP is the mechanism name of IMS IPsec.
+const MechanismIPsec3GPP = "ipsec-3gpp"
+
+// Integrity algorithm definitions.
+const (
+	AlgHMACMD596  = "hmac-md5-96"
+	AlgHMACSHA196 = "hmac-sha-1-96"
+)
+
+// Encryption algorithm definitions.
+const (
+	EAlgNull       = "null"
+	EAlgDESEDE3CBC = "des-ede3-cbc"
+	EAlgAESCBC     = "aes-cbc"
+)
+
+// IntegrityKey derives IK_ESP for the integrity algorithm alg from IK (I.2, TS 33.203).
+//
+// IK is used as is for hmac-md5-96, and padded with 32 zero bits for hmac-sha-1-96.
+func IntegrityKey(alg string, ik []byte) ([]byte, error) {
+	if len(ik) != 16 {
+		return nil, fmt.Errorf("length of IK should be %d, got: %d", 16, len(ik))
+	}
+
+	switch strings.ToLower(alg) {
+	case AlgHMACMD596:
+		return append([]byte{}, ik...), nil
+	case AlgHMACSHA196:
+		return append(append([]byte{}, ik...), 0, 0, 0, 0), nil
+	default:
+		return nil, fmt.Errorf("unsupported integrity algorithm: %s", alg)
+	}
+}
+
+// EncryptionKey derives CK_ESP for the encryption algorithm ealg from CK (I.3, TS 33.203).
+//
+// CK is used as is for aes-cbc, and CK1 || CK2 || CK1 is used for des-ede3-cbc where
+// CK1 and CK2 are the first and the last 64 bits of CK (the parity bits are left as is).
+// nil is returned for null.
+func EncryptionKey(ealg string, ck []byte) ([]byte, error) {
+	if len(ck) != 16 {
+		return nil, fmt.Errorf("length of CK should be %d, got: %d", 16, len(ck))
+	}
+
+	switch strings.ToLower(ealg) {
+	case EAlgNull, "":
+		return nil, nil
+	case EAlgAESCBC:
+		return append([]byte{}, ck...), nil
+	case EAlgDESEDE3CBC:
+		return append(append([]byte{}, ck...), ck[:8]...), nil
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm: %s", ealg)
+	}
+}
+
+// SecurityMechanism is a sec-mechanism in the Security-Client, Security-Server or
+// Security-Verify header.
+type SecurityMechanism struct {
+	Mechanism string
+	Alg       string
+	// EAlg is the encryption algorithm, which means null if empty.
+	EAlg  string
+	SPIC  uint32
+	SPIS  uint32
+	PortC uint16
+	PortS uint16
+	Prot  string
+	Mod   string
+	// Q is the preference value, which is omitted if empty.
+	Q string
+	// Params is the other parameters, which are kept as is in the order.
+	Params []string
+}
+
+// ParseSecurityHeader parses the value of Security-Client, Security-Server or
+// Security-Verify header, which can contain multiple mechanisms.
+func ParseSecurityHeader(s string) ([]*SecurityMechanism, error) {
+	var ms []*SecurityMechanism
+	for _, v := range strings.Split(s, ",") {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		m, err := ParseSecurityMechanism(v)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// ParseSecurityMechanism parses a sec-mechanism.
+func ParseSecurityMechanism(s string) (*SecurityMechanism, error) {
+	params := strings.Split(s, ";")
+	m := &SecurityMechanism{Mechanism: strings.TrimSpace(params[0])}
+	if m.Mechanism == "" {
+		return nil, fmt.Errorf("missing mechanism name: %s", s)
+	}
+
+	for _, p := range params[1:] {
+		p = strings.TrimSpace(p)
+		key, value, _ := strings.Cut(p, "=")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "alg":
+			m.Alg = value
+		case "ealg":
+			m.EAlg = value
+		case "spi-c":
+			m.SPIC, err = parseUint32(key, value)
+		case "spi-s":
+			m.SPIS, err = parseUint32(key, value)
+		case "port-c":
+			m.PortC, err = parsePort(key, value)
+		case "port-s":
+			m.PortS, err = parsePort(key, value)
+		case "prot":
+			m.Prot = value
+		case "mod":
+			m.Mod = value
+		case "q":
+			m.Q = value
+		default:
+			m.Params = append(m.Params, p)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// String returns m as a sec-mechanism.
+func (m *SecurityMechanism) String() string {
+	var sb strings.Builder
+	sb.WriteString(m.Mechanism)
+	if m.Q != "" {
+		sb.WriteString(";q=" + m.Q)
+	}
+	if m.Alg != "" {
+		sb.WriteString(";alg=" + m.Alg)
+	}
+	if m.EAlg != "" {
+		sb.WriteString(";ealg=" + m.EAlg)
+	}
+	if m.Prot != "" {
+		sb.WriteString(";prot=" + m.Prot)
+	}
+	if m.Mod != "" {
+		sb.WriteString(";mod=" + m.Mod)
+	}
+	fmt.Fprintf(&sb, ";spi-c=%d;spi-s=%d;port-c=%d;port-s=%d", m.SPIC, m.SPIS, m.PortC, m.PortS)
+	for _, p := range m.Params {
+		sb.WriteString(";" + p)
+	}
+	return sb.String()
+}
+
+// FormatSecurityHeader returns ms as the value of the security headers.
+func FormatSecurityHeader(ms ...*SecurityMechanism) string {
+	s := make([]string, len(ms))
+	for i, m := range ms {
+		s[i] = m.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// SA is a unidirectional IPsec security association between UE and P-CSCF.
+type SA struct {
+	// FromUE is true if the SA is for the packets from UE to P-CSCF.
+	FromUE bool
+	// SPI is the SPI chosen by the receiver.
+	SPI     uint32
+	SrcPort uint16
+	DstPort uint16
+
+	Alg           string
+	EAlg          string
+	IntegrityKey  []byte
+	EncryptionKey []byte
+}
+
+// SecurityAssociations derives the four SAs (7.1, TS 33.203) from the mechanism in
+// Security-Client sent by UE and the one in Security-Server sent by P-CSCF, with CK and
+// IK from the AKA exchange. The algorithms in server, which are selected by P-CSCF, are used.
+//
+// The SAs are returned in the order of UE port-c to P-CSCF port-s, P-CSCF port-c to
+// UE port-s, P-CSCF port-s to UE port-c, and UE port-s to P-CSCF port-c.
+func SecurityAssociations(client, server *SecurityMechanism, ck, ik []byte) ([]*SA, error) {
+	ikESP, err := IntegrityKey(server.Alg, ik)
+	if err != nil {
+		return nil, err
+	}
+	ckESP, err := EncryptionKey(server.EAlg, ck)
+	if err != nil {
+		return nil, err
+	}
+
+	sa := func(fromUE bool, spi uint32, src, dst uint16) *SA {
+		return &SA{
+			FromUE:        fromUE,
+			SPI:           spi,
+			SrcPort:       src,
+			DstPort:       dst,
+			Alg:           server.Alg,
+			EAlg:          server.EAlg,
+			IntegrityKey:  ikESP,
+			EncryptionKey: ckESP,
+		}
+	}
+	return []*SA{
+		sa(true, server.SPIS, client.PortC, server.PortS),
+		sa(false, client.SPIS, server.PortC, client.PortS),
+		sa(false, client.SPIC, server.PortS, client.PortC),
+		sa(true, server.SPIC, client.PortS, server.PortC),
+	}, nil
+}
+
+func parseUint32(key, value string) (uint32, error) {
+	v, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return uint32(v), nil
+}
+
+func parsePort(key, value string) (uint16, error) {
+	v, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return uint16(v), nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package imsipsec_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage/imsipsec"
+)
+
+var (
+	ck = "b40ba9a3c58b2a05bbf0d987b21bf8cb"
+	ik = "f769bcd751044604127672711c6d3441"
+)
+
+func TestKeys(t *testing.T) {
+	for _, c := range []struct {
+		alg string
+		key func(alg string, k []byte) ([]byte, error)
+		in  string
+		out string
+	}{
+		{imsipsec.AlgHMACMD596, imsipsec.IntegrityKey, ik, ik},
+		{imsipsec.AlgHMACSHA196, imsipsec.IntegrityKey, ik, ik + "00000000"},
+		{imsipsec.EAlgNull, imsipsec.EncryptionKey, ck, ""},
+		{imsipsec.EAlgAESCBC, imsipsec.EncryptionKey, ck, ck},
+		{imsipsec.EAlgDESEDE3CBC, imsipsec.EncryptionKey, ck, ck + ck[:16]},
+	} {
+		in, err := hex.DecodeString(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.key(c.alg, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(got) != c.out {
+			t.Errorf("%s failed: unexpected key: %x", c.alg, got)
+		}
+	}
+
+	ikb, err := hex.DecodeString(ik)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imsipsec.IntegrityKey("hmac-sha-256-128", ikb); err == nil {
+		t.Error("expected error with unsupported algorithm")
+	}
+	if _, err := imsipsec.EncryptionKey(imsipsec.EAlgAESCBC, ikb[:8]); err == nil {
+		t.Error("expected error with invalid CK")
+	}
+}
+
+func TestParseSecurityHeader(t *testing.T) {
+	s := "ipsec-3gpp; alg=hmac-sha-1-96; ealg=aes-cbc; spi-c=1111; spi-s=2222; port-c=5062; port-s=5064; prot=esp; mod=trans; q=0.1, " +
+		"ipsec-3gpp;alg=hmac-md5-96;spi-c=3333;spi-s=4444;port-c=5066;port-s=5068;foo"
+	got, err := imsipsec.ParseSecurityHeader(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*imsipsec.SecurityMechanism{
+		{
+			Mechanism: imsipsec.MechanismIPsec3GPP,
+			Alg:       imsipsec.AlgHMACSHA196,
+			EAlg:      imsipsec.EAlgAESCBC,
+			SPIC:      1111,
+			SPIS:      2222,
+			PortC:     5062,
+			PortS:     5064,
+			Prot:      "esp",
+			Mod:       "trans",
+			Q:         "0.1",
+		}, {
+			Mechanism: imsipsec.MechanismIPsec3GPP,
+			Alg:       imsipsec.AlgHMACMD596,
+			SPIC:      3333,
+			SPIS:      4444,
+			PortC:     5066,
+			PortS:     5068,
+			Params:    []string{"foo"},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error(diff)
+	}
+
+	formatted := imsipsec.FormatSecurityHeader(got...)
+	if formatted != "ipsec-3gpp;q=0.1;alg=hmac-sha-1-96;ealg=aes-cbc;prot=esp;mod=trans;spi-c=1111;spi-s=2222;port-c=5062;port-s=5064, "+
+		"ipsec-3gpp;alg=hmac-md5-96;spi-c=3333;spi-s=4444;port-c=5066;port-s=5068;foo" {
+		t.Errorf("unexpected header: %s", formatted)
+	}
+	again, err := imsipsec.ParseSecurityHeader(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(again, want); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, s := range []string{
+		"ipsec-3gpp; spi-c=4294967296",
+		"ipsec-3gpp; port-s=65536",
+		"; alg=hmac-md5-96",
+	} {
+		if _, err := imsipsec.ParseSecurityHeader(s); err == nil {
+			t.Errorf("expected error with %s", s)
+		}
+	}
+}
+
+func TestSecurityAssociations(t *testing.T) {
+	client := &imsipsec.SecurityMechanism{
+		Mechanism: imsipsec.MechanismIPsec3GPP, Alg: imsipsec.AlgHMACSHA196, EAlg: imsipsec.EAlgAESCBC,
+		SPIC: 1111, SPIS: 2222, PortC: 5062, PortS: 5064,
+	}
+	server := &imsipsec.SecurityMechanism{
+		Mechanism: imsipsec.MechanismIPsec3GPP, Alg: imsipsec.AlgHMACSHA196,
+		SPIC: 3333, SPIS: 4444, PortC: 5066, PortS: 5068,
+	}
+
+	ckb, err := hex.DecodeString(ck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ikb, err := hex.DecodeString(ik)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sas, err := imsipsec.SecurityAssociations(client, server, ckb, ikb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ikESP, err := hex.DecodeString(ik + "00000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa := func(fromUE bool, spi uint32, src, dst uint16) *imsipsec.SA {
+		return &imsipsec.SA{FromUE: fromUE, SPI: spi, SrcPort: src, DstPort: dst, Alg: imsipsec.AlgHMACSHA196, IntegrityKey: ikESP}
+	}
+	want := []*imsipsec.SA{
+		sa(true, 4444, 5062, 5068),
+		sa(false, 2222, 5066, 5064),
+		sa(false, 1111, 5068, 5062),
+		sa(true, 3333, 5064, 5066),
+	}
+	if diff := cmp.Diff(sas, want); diff != "" {
+		t.Error(diff)
+	}
+}
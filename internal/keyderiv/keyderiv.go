@@ -0,0 +1,50 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package keyderiv provides the derivations shared by the EPS (TS 33.401) and 5GS (TS 33.501)
+key hierarchies, which are the same in the two systems except for FC.
+*/
+package keyderiv
+
+import "github.com/wmnsk/milenage"
+
+// AlgorithmKey derives the 128-bit algorithm key from key with FC, the algorithm type
+// distinguisher and the algorithm identity (A.7, TS 33.401 and A.8, TS 33.501).
+// The 128 least significant bits of the KDF output are used.
+func AlgorithmKey(key []byte, fc, distinguisher, algID uint8) []byte {
+	out := milenage.KDF(key, fc, []byte{distinguisher}, []byte{algID})
+	return out[16:]
+}
+
+// NHChain keeps the NH and NCC pair derived from key with FC.
+type NHChain struct {
+	fc  uint8
+	key []byte
+	nh  []byte
+	ncc uint8
+}
+
+// NewNHChain creates a new NHChain with the initial NH, which is the key for the access
+// stratum derived at the initial context setup, and NCC zero.
+func NewNHChain(fc uint8, key, initial []byte) *NHChain {
+	return &NHChain{
+		fc:  fc,
+		key: append([]byte{}, key...),
+		nh:  append([]byte{}, initial...),
+	}
+}
+
+// NH returns the current NH and NCC.
+func (c *NHChain) NH() (nh []byte, ncc uint8) {
+	return append([]byte{}, c.nh...), c.ncc
+}
+
+// Next derives the next NH from the current one, and returns it with NCC incremented.
+// NCC is 3 bits and wraps around to zero after seven.
+func (c *NHChain) Next() (nh []byte, ncc uint8) {
+	c.nh = milenage.KDF(c.key, c.fc, c.nh)
+	c.ncc = (c.ncc + 1) & 0x07
+	return c.NH()
+}
@@ -0,0 +1,142 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// KDF is the generic key derivation function defined in B.2.0, TS 33.220,
+// which computes HMAC-SHA-256(key, FC || P0 || L0 || P1 || L1 || ...).
+// Each Ln is the length of Pn in two octets, which is computed from the params given.
+func KDF(key []byte, fc byte, params ...[]byte) []byte {
+	l := 1
+	for _, p := range params {
+		l += len(p) + 2
+	}
+
+	s := make([]byte, 0, l)
+	s = append(s, fc)
+	for _, p := range params {
+		s = append(s, p...)
+		s = binary.BigEndian.AppendUint16(s, uint16(len(p)))
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(s)
+	return mac.Sum(nil)
+}
+
+// ComputeKAUSF computes KAUSF from serving network name, CK, IK and SQN xor AK
+// as described in A.2 KAUSF derivation function, TS 33.501.
+//
+// Note that this function should be called after F2345 is done (to generate CK, IK and AK).
+func (m *Milenage) ComputeKAUSF(mcc, mnc string) ([]byte, error) {
+	if err := m.validateLength(); err != nil {
+		return nil, err
+	}
+
+	p, err := NewPLMN(mcc, mnc)
+	if err != nil {
+		return nil, err
+	}
+	return m.ComputeKAUSFWithSNN(p.ServingNetworkName())
+}
+
+// ComputeKAUSFWithSNN computes KAUSF in the same way as ComputeKAUSF, with the serving
+// network name given as it is, e.g., the one of SNPN including NID.
+func (m *Milenage) ComputeKAUSFWithSNN(snn string) ([]byte, error) {
+	if err := m.validateLength(); err != nil {
+		return nil, err
+	}
+	if err := ValidateServingNetworkName(snn); err != nil {
+		return nil, err
+	}
+
+	return KDF(m.ckik(), 0x6a, []byte(snn), xor(m.SQN, m.AK)), nil
+}
+
+// ComputeKASME computes KASME from CK, IK, the serving network identity (PLMN ID of
+// the serving network) and SQN xor AK as described in A.2 KASME derivation function,
+// TS 33.401.
+//
+// Note that this function should be called after F2345 is done (to generate CK, IK and AK).
+func (m *Milenage) ComputeKASME(mcc, mnc string) ([]byte, error) {
+	if err := m.validateLength(); err != nil {
+		return nil, err
+	}
+
+	p, err := NewPLMN(mcc, mnc)
+	if err != nil {
+		return nil, err
+	}
+
+	return KDF(m.ckik(), 0x10, p.BCD(), xor(m.SQN, m.AK)), nil
+}
+
+// ComputeCKIKPrime computes CK' and IK' from CK, IK, the access network identity and
+// SQN xor AK as described in A.2, TS 33.402, which are used in EAP-AKA'.
+//
+// In 5G, the serving network name is used as the access network identity (6.1.3.1, TS 33.501).
+// Note that this function should be called after F2345 is done (to generate CK, IK and AK).
+func (m *Milenage) ComputeCKIKPrime(anid string) (ckPrime, ikPrime []byte, err error) {
+	if err := m.validateLength(); err != nil {
+		return nil, nil, err
+	}
+	if anid == "" {
+		return nil, nil, errors.New("access network identity should not be empty")
+	}
+
+	out := KDF(m.ckik(), 0x20, []byte(anid), xor(m.SQN, m.AK))
+	return out[:16], out[16:], nil
+}
+
+// ComputeHXRESStar computes HXRES* (or HRES*) from RAND and XRES* (or RES*) as described
+// in A.5 HRES* and HXRES* derivation function, TS 33.501.
+func ComputeHXRESStar(rand, xresStar []byte) []byte {
+	h := sha256.New()
+	h.Write(rand)
+	h.Write(xresStar)
+	out := h.Sum(nil)
+	return out[len(out)-16:]
+}
+
+// ComputeKSEAF computes KSEAF from KAUSF and serving network name as described
+// in A.6 KSEAF derivation function, TS 33.501.
+func ComputeKSEAF(kausf []byte, mcc, mnc string) ([]byte, error) {
+	if len(kausf) != 32 {
+		return nil, fmt.Errorf("length of KAUSF should be %d, got: %d", 32, len(kausf))
+	}
+
+	p, err := NewPLMN(mcc, mnc)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeKSEAFWithSNN(kausf, p.ServingNetworkName())
+}
+
+// ComputeKSEAFWithSNN computes KSEAF in the same way as ComputeKSEAF, with the serving
+// network name given as it is, e.g., the one of SNPN including NID.
+func ComputeKSEAFWithSNN(kausf []byte, snn string) ([]byte, error) {
+	if len(kausf) != 32 {
+		return nil, fmt.Errorf("length of KAUSF should be %d, got: %d", 32, len(kausf))
+	}
+	if err := ValidateServingNetworkName(snn); err != nil {
+		return nil, err
+	}
+	return KDF(kausf, 0x6c, []byte(snn)), nil
+}
+
+// ckik returns CK || IK, which is the key of the KDFs for 5G and EPS.
+func (m *Milenage) ckik() []byte {
+	k := make([]byte, 32)
+	copy(k[0:16], m.CK)
+	copy(k[16:32], m.IK)
+	return k
+}
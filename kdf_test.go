@@ -0,0 +1,175 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+)
+
+func TestComputeKAUSF(t *testing.T) {
+	c := cases[0]
+	if err := c.input.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.input.ComputeKAUSF("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := mustDecodeHex(t, "3b759becc904d5b2aad2fcf15c88ce4354ade608ebbd6d89aa1c3281564c56f8")
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("%s failed: \n%s", c.description, diff)
+	}
+
+	if _, err := c.input.ComputeKAUSF("01", "01"); err == nil {
+		t.Error("expected error with invalid MCC")
+	}
+}
+
+func TestComputeWithSNN(t *testing.T) {
+	c := cases[0]
+	if err := c.input.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sc := range []struct {
+		description string
+		snn         string
+		resStar     string
+		kausf       string
+		kseaf       string
+	}{
+		{
+			"PLMN", "5G:mnc001.mcc001.3gppnetwork.org",
+			hex.EncodeToString(c.expected.mil.RESStar),
+			"3b759becc904d5b2aad2fcf15c88ce4354ade608ebbd6d89aa1c3281564c56f8",
+			"a1ca0731bbc80913ea613972c75e2782d02b7a13c0b235c98cc5778e4520b944",
+		}, {
+			"SNPN", "5G:mnc001.mcc001.3gppnetwork.org:00000000001",
+			"7dc429c38d4bda5fc3f116510627bb9c",
+			"a90affce1d967a090fdcc72bcb6851becf28f84da732cf45e54e941352bc6ae6",
+			"b7c5b4d27950f46b81fd67070fde0a92c6cf5e31d6f79c2ee9e0c4504f55fdad",
+		},
+	} {
+		resStar, err := c.input.ComputeRESStarWithSNN(sc.snn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(resStar, mustDecodeHex(t, sc.resStar)); diff != "" {
+			t.Errorf("%s failed: \n%s", sc.description+"/RESStar", diff)
+		}
+
+		kausf, err := c.input.ComputeKAUSFWithSNN(sc.snn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(kausf, mustDecodeHex(t, sc.kausf)); diff != "" {
+			t.Errorf("%s failed: \n%s", sc.description+"/KAUSF", diff)
+		}
+
+		kseaf, err := milenage.ComputeKSEAFWithSNN(kausf, sc.snn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(kseaf, mustDecodeHex(t, sc.kseaf)); diff != "" {
+			t.Errorf("%s failed: \n%s", sc.description+"/KSEAF", diff)
+		}
+	}
+
+	resStar, err := c.input.ComputeRESStarWithSNN(milenage.ServingNetworkNameNSWO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(resStar, mustDecodeHex(t, "5d6c40ba93603ef33a368a689d315d4d")); diff != "" {
+		t.Errorf("%s failed: \n%s", "NSWO/RESStar", diff)
+	}
+
+	if _, err := c.input.ComputeRESStarWithSNN(""); err == nil {
+		t.Error("expected error with empty SNN")
+	}
+}
+
+func TestComputeKASME(t *testing.T) {
+	c := cases[0]
+	if err := c.input.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.input.ComputeKASME("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := mustDecodeHex(t, "2bfb4cb74ad78a28536f0b219cfe9d4fa1279aac27532e11113ff8dda81e6e04")
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("%s failed: \n%s", c.description, diff)
+	}
+
+	if _, err := c.input.ComputeKASME("001", "0a"); err == nil {
+		t.Error("expected error with invalid MNC")
+	}
+}
+
+func TestKDF(t *testing.T) {
+	// RES* is also derived with KDF, with FC=0x6B.
+	c := cases[0]
+	if err := c.input.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	key := append(append([]byte{}, c.input.CK...), c.input.IK...)
+	got := milenage.KDF(key, 0x6b, []byte("5G:mnc001.mcc001.3gppnetwork.org"), c.input.RAND, c.input.RES)
+	if diff := cmp.Diff(got[16:], c.expected.mil.RESStar); diff != "" {
+		t.Errorf("%s failed: \n%s", c.description, diff)
+	}
+}
+
+func TestComputeCKIKPrime(t *testing.T) {
+	// Test case 1 in Appendix C, RFC 5448.
+	// SQN xor AK = bb52e91c747a.
+	m := milenage.NewWithOPc(make([]byte, 16), make([]byte, 16), make([]byte, 16), 0xbb52e91c747a, 0)
+	m.CK = mustDecodeHex(t, "5349fbe098649f948f5d2e973a81c00f")
+	m.IK = mustDecodeHex(t, "9744871ad32bf9bbd1dd5ce54e3e2e5a")
+
+	ckPrime, ikPrime, err := m.ComputeCKIKPrime("WLAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(ckPrime, mustDecodeHex(t, "0093962d0dd84aa5684b045c9edffa04")); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(ikPrime, mustDecodeHex(t, "ccfc230ca74fcc96c0a5d61164f5a76c")); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestComputeHXRESStarAndKSEAF(t *testing.T) {
+	c := cases[0]
+	if err := c.input.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := milenage.ComputeHXRESStar(c.input.RAND, c.expected.mil.RESStar)
+	if diff := cmp.Diff(got, mustDecodeHex(t, "3308fb7cf06a35f1cd086b904ce82ecf")); diff != "" {
+		t.Errorf("%s failed: \n%s", c.description+"/HXRES*", diff)
+	}
+
+	kausf, err := c.input.ComputeKAUSF("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kseaf, err := milenage.ComputeKSEAF(kausf, "001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(kseaf, mustDecodeHex(t, "a1ca0731bbc80913ea613972c75e2782d02b7a13c0b235c98cc5778e4520b944")); diff != "" {
+		t.Errorf("%s failed: \n%s", c.description+"/KSEAF", diff)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// Kernel is the block cipher kernel of MILENAGE, which encrypts a 128-bit block
+// under the subscriber key K.
+//
+// Implement Kernel to keep K behind an opaque key handle, e.g., in HSM. Kernel
+// should be safe for concurrent use if it is shared among Milenage or Cipher used
+// by multiple goroutines.
+type Kernel interface {
+	// EncryptBlock encrypts src into dst under K. dst and src may point at the same memory.
+	EncryptBlock(dst, src *[16]byte) error
+}
+
+// SoftwareKernel is a Kernel that holds the expanded K in the memory and
+// encrypts with crypto/aes.
+type SoftwareKernel struct {
+	block cipher.Block
+}
+
+// NewSoftwareKernel creates a new SoftwareKernel from K.
+func NewSoftwareKernel(k []byte) (*SoftwareKernel, error) {
+	if len(k) != 16 {
+		return nil, fmt.Errorf("length of K should be %d, got: %d", 16, len(k))
+	}
+
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return &SoftwareKernel{block: block}, nil
+}
+
+// EncryptBlock encrypts src into dst under K.
+func (k *SoftwareKernel) EncryptBlock(dst, src *[16]byte) error {
+	k.block.Encrypt(dst[:], src[:])
+	return nil
+}
+
+// SealedKernel is a Kernel that keeps K sealed under an ephemeral key generated at
+// creation, and unseals it only while encrypting a block.
+//
+// SealedKernel is meant to emulate an opaque key handle in the tests of the
+// code that uses Kernel, and it does NOT provide protection comparable to HSM.
+type SealedKernel struct {
+	seal   cipher.Block
+	sealed [16]byte
+}
+
+// NewSealedKernel creates a new SealedKernel from K.
+//
+// K is not kept in the memory after this function returns; it is the caller's
+// responsibility to clear k if needed.
+func NewSealedKernel(k []byte) (*SealedKernel, error) {
+	if len(k) != 16 {
+		return nil, fmt.Errorf("length of K should be %d, got: %d", 16, len(k))
+	}
+
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate sealing key: %w", err)
+	}
+	seal, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	clear(key[:])
+
+	s := &SealedKernel{seal: seal}
+	seal.Encrypt(s.sealed[:], k)
+	return s, nil
+}
+
+// EncryptBlock unseals K, encrypts src into dst under K, and clears the unsealed K.
+func (k *SealedKernel) EncryptBlock(dst, src *[16]byte) error {
+	var key [16]byte
+	k.seal.Decrypt(key[:], k.sealed[:])
+	defer clear(key[:])
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return err
+	}
+	block.Encrypt(dst[:], src[:])
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/wmnsk/milenage"
+)
+
+// countingKernel counts the number of blocks encrypted, or fails if err is set.
+type countingKernel struct {
+	milenage.Kernel
+	count int
+	err   error
+}
+
+func (k *countingKernel) EncryptBlock(dst, src *[16]byte) error {
+	if k.err != nil {
+		return k.err
+	}
+	k.count++
+	return k.Kernel.EncryptBlock(dst, src)
+}
+
+func TestSealedKernel(t *testing.T) {
+	for _, c := range cases {
+		e := c.expected.mil
+		kernel, err := milenage.NewSealedKernel(e.K)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if e.OP != nil {
+			opc, err := milenage.ComputeOPcWithKernel(kernel, e.OP)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(opc, e.OPc); diff != "" {
+				t.Errorf("%s failed: \n%s", c.description+"/OPc", diff)
+			}
+		}
+
+		m := milenage.NewWithKernel(kernel, e.OPc, e.RAND, milenage.SQNToUint64([6]byte(e.SQN)), 0)
+		m.AMF = e.AMF
+		if err := m.ComputeAll(); err != nil {
+			t.Fatal(err)
+		}
+
+		if m.Kernel != kernel {
+			t.Errorf("%s failed: Kernel is not set", c.description)
+		}
+
+		// Kernel cannot be compared as it has unexported fields.
+		got, want := *m, *e
+		got.Kernel = nil
+		want.K, want.OP, want.RESStar = nil, nil, nil
+		if diff := cmp.Diff(got, want, cmpopts.IgnoreUnexported(milenage.Milenage{})); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+
+		autn, err := m.GenerateAUTN()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(autn, c.expected.autn); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/AUTN", diff)
+		}
+	}
+}
+
+func TestCipherWithKernel(t *testing.T) {
+	e := cases[2].expected.mil
+	sw, err := milenage.NewSoftwareKernel(e.K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kernel := &countingKernel{Kernel: sw}
+
+	ci, err := milenage.NewCipherWithKernel(kernel, e.OPc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v milenage.Vector
+	rand, sqn, amf := [16]byte(e.RAND), [6]byte(e.SQN), [2]byte(e.AMF)
+	if err := ci.ComputeQuintet(&v, &rand, &sqn, &amf); err != nil {
+		t.Fatal(err)
+	}
+	if kernel.count != 5 {
+		t.Errorf("unexpected number of block operations: %d", kernel.count)
+	}
+	if diff := cmp.Diff(v.AUTN[:], cases[2].expected.autn); diff != "" {
+		t.Error(diff)
+	}
+
+	kernel.err = errors.New("HSM unavailable")
+	if err := ci.Compute(&v, &rand, &sqn, &amf); !errors.Is(err, kernel.err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	m := milenage.NewWithKernel(kernel, e.OPc, e.RAND, 0, 0)
+	if _, err := m.F1(); !errors.Is(err, kernel.err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := milenage.NewCipherWithKernel(nil, e.OPc); err == nil {
+		t.Error("expected error with nil kernel")
+	}
+}
+
+func TestKeyReplaced(t *testing.T) {
+	e := cases[2].expected.mil
+
+	// the key schedule built from the previous K should not be used.
+	for _, replace := range []func(m *milenage.Milenage){
+		func(m *milenage.Milenage) { m.K = append([]byte(nil), e.K...) },
+		func(m *milenage.Milenage) { m.Wipe(); copy(m.K, e.K); copy(m.OPc, e.OPc) },
+	} {
+		m := milenage.NewWithOPc(
+			append([]byte(nil), cases[0].expected.mil.K...), append([]byte(nil), e.OPc...), e.RAND,
+			milenage.SQNToUint64([6]byte(e.SQN)), 0xb9b9,
+		)
+		if _, err := m.F1(); err != nil {
+			t.Fatal(err)
+		}
+
+		replace(m)
+		macA, err := m.F1()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(macA, e.MACA); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrUnwrapFailed is returned when the integrity check fails on unwrapping,
+// which means that either the KEK or the wrapped key is wrong.
+var ErrUnwrapFailed = errors.New("integrity check failed on unwrapping key")
+
+var (
+	// defaultIV is the default initial value defined in 2.2.3.1, RFC 3394.
+	defaultIV = [8]byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+	// aivPrefix is the constant part of the alternative initial value defined in 3, RFC 5649.
+	aivPrefix = [4]byte{0xa6, 0x59, 0x59, 0xa6}
+)
+
+// WrapKey wraps key under kek with AES Key Wrap algorithm defined in RFC 3394.
+// The length of key should be a multiple of 8 and at least 16, and kek should be
+// a valid AES key (16, 24 or 32 bytes).
+func WrapKey(kek, key []byte) ([]byte, error) {
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, fmt.Errorf("length of key to wrap should be a multiple of 8 and at least 16, got: %d", len(key))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(block, defaultIV, key), nil
+}
+
+// UnwrapKey unwraps wrapped under kek with AES Key Wrap algorithm defined in RFC 3394.
+// ErrUnwrapFailed is returned if the integrity check fails.
+func UnwrapKey(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("length of wrapped key should be a multiple of 8 and at least 24, got: %d", len(wrapped))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	a, key := unwrap(block, wrapped)
+	if subtle.ConstantTimeCompare(a[:], defaultIV[:]) != 1 {
+		clear(key)
+		return nil, ErrUnwrapFailed
+	}
+	return key, nil
+}
+
+// WrapKeyWithPadding wraps key under kek with AES Key Wrap with Padding algorithm
+// defined in RFC 5649. key can be any length from 1 to 2^32-1 bytes.
+func WrapKeyWithPadding(kek, key []byte) ([]byte, error) {
+	if len(key) == 0 || uint64(len(key)) > 0xffffffff {
+		return nil, fmt.Errorf("invalid length of key to wrap: %d", len(key))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	var aiv [8]byte
+	copy(aiv[:4], aivPrefix[:])
+	binary.BigEndian.PutUint32(aiv[4:], uint32(len(key)))
+
+	padded := make([]byte, (len(key)+7)/8*8)
+	copy(padded, key)
+	defer clear(padded)
+
+	// A single block is simply encrypted with AIV (4.1, RFC 5649).
+	if len(padded) == 8 {
+		out := make([]byte, 16)
+		copy(out[:8], aiv[:])
+		copy(out[8:], padded)
+		block.Encrypt(out, out)
+		return out, nil
+	}
+	return wrap(block, aiv, padded), nil
+}
+
+// UnwrapKeyWithPadding unwraps wrapped under kek with AES Key Wrap with Padding
+// algorithm defined in RFC 5649. ErrUnwrapFailed is returned if the integrity check fails.
+func UnwrapKeyWithPadding(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("length of wrapped key should be a multiple of 8 and at least 16, got: %d", len(wrapped))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		a      [8]byte
+		padded []byte
+	)
+	if len(wrapped) == 16 {
+		b := make([]byte, 16)
+		block.Decrypt(b, wrapped)
+		copy(a[:], b[:8])
+		padded = b[8:]
+	} else {
+		a, padded = unwrap(block, wrapped)
+	}
+
+	key, ok := checkAIV(a, padded)
+	if !ok {
+		clear(padded)
+		return nil, ErrUnwrapFailed
+	}
+	return key, nil
+}
+
+// NewWithWrappedOPc initializes a new MILENAGE algorithm with K and OPc wrapped under kek.
+// Both RFC 3394 and RFC 5649 formats are accepted for each of wrappedK and wrappedOPc.
+func NewWithWrappedOPc(kek, wrappedK, wrappedOPc, rand []byte, sqn uint64, amf uint16) (*Milenage, error) {
+	k, err := unwrapAny(kek, wrappedK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap K: %w", err)
+	}
+	opc, err := unwrapAny(kek, wrappedOPc)
+	if err != nil {
+		clear(k)
+		return nil, fmt.Errorf("failed to unwrap OPc: %w", err)
+	}
+
+	m := NewWithOPc(k, opc, rand, sqn, amf)
+	if err := m.validateLength(); err != nil {
+		// the unwrapped keys are not used by anyone, and should not be left in memory.
+		clear(k)
+		clear(opc)
+		return nil, err
+	}
+	return m, nil
+}
+
+// unwrapAny unwraps wrapped in either of RFC 3394 or RFC 5649 format.
+func unwrapAny(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) == 16 {
+		return UnwrapKeyWithPadding(kek, wrapped)
+	}
+
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("length of wrapped key should be a multiple of 8 and at least 16, got: %d", len(wrapped))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	a, key := unwrap(block, wrapped)
+	if subtle.ConstantTimeCompare(a[:], defaultIV[:]) == 1 {
+		return key, nil
+	}
+	if k, ok := checkAIV(a, key); ok {
+		return k, nil
+	}
+
+	clear(key)
+	return nil, ErrUnwrapFailed
+}
+
+// checkAIV checks if a is the valid AIV for padded defined in 3, RFC 5649,
+// and returns the key without padding.
+func checkAIV(a [8]byte, padded []byte) ([]byte, bool) {
+	if subtle.ConstantTimeCompare(a[:4], aivPrefix[:]) != 1 {
+		return nil, false
+	}
+
+	mli := int(binary.BigEndian.Uint32(a[4:]))
+	if mli <= len(padded)-8 || mli > len(padded) {
+		return nil, false
+	}
+	for _, b := range padded[mli:] {
+		if b != 0 {
+			return nil, false
+		}
+	}
+	return padded[:mli], true
+}
+
+// wrap is the wrapping process defined in 2.2.1, RFC 3394 (index based).
+func wrap(block cipher.Block, iv [8]byte, key []byte) []byte {
+	n := len(key) / 8
+	out := make([]byte, 8+len(key))
+	copy(out[8:], key)
+
+	var b [16]byte
+	copy(b[:8], iv[:])
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			r := out[i*8 : i*8+8]
+			copy(b[8:], r)
+			block.Encrypt(b[:], b[:])
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(b[:8])^t)
+			copy(r, b[8:])
+		}
+	}
+	copy(out[:8], b[:8])
+	clear(b[:])
+
+	return out
+}
+
+// unwrap is the unwrapping process defined in 2.2.2, RFC 3394 (index based).
+// It returns the resulting A and the key, and the caller is responsible for checking A.
+func unwrap(block cipher.Block, wrapped []byte) ([8]byte, []byte) {
+	n := len(wrapped)/8 - 1
+	key := make([]byte, len(wrapped)-8)
+	copy(key, wrapped[8:])
+
+	var b [16]byte
+	copy(b[:8], wrapped[:8])
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			r := key[(i-1)*8 : i*8]
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(b[:8])^t)
+			copy(b[8:], r)
+			block.Decrypt(b[:], b[:])
+			copy(r, b[8:])
+		}
+	}
+
+	var a [8]byte
+	copy(a[:], b[:8])
+	clear(b[:])
+
+	return a, key
+}
@@ -0,0 +1,117 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestKeyWrap(t *testing.T) {
+	for _, c := range []struct {
+		description string
+		kek, key    string
+		wrapped     string
+		wrap        func(kek, key []byte) ([]byte, error)
+		unwrap      func(kek, wrapped []byte) ([]byte, error)
+	}{
+		{
+			"RFC3394/4.1",
+			"000102030405060708090a0b0c0d0e0f",
+			"00112233445566778899aabbccddeeff",
+			"1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5",
+			milenage.WrapKey, milenage.UnwrapKey,
+		}, {
+			"RFC3394/4.6",
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f",
+			"28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21",
+			milenage.WrapKey, milenage.UnwrapKey,
+		}, {
+			"RFC5649/6/20 octets",
+			"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+			"c37b7e6492584340bed12207808941155068f738",
+			"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+			milenage.WrapKeyWithPadding, milenage.UnwrapKeyWithPadding,
+		}, {
+			"RFC5649/6/7 octets",
+			"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+			"466f7250617369",
+			"afbeb0f07dfbf5419200f2ccb50bb24f",
+			milenage.WrapKeyWithPadding, milenage.UnwrapKeyWithPadding,
+		},
+	} {
+		kek, key, wrapped := mustDecodeHex(t, c.kek), mustDecodeHex(t, c.key), mustDecodeHex(t, c.wrapped)
+
+		got, err := c.wrap(kek, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, wrapped); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/wrap", diff)
+		}
+
+		got, err = c.unwrap(kek, wrapped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, key); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/unwrap", diff)
+		}
+
+		wrapped[len(wrapped)-1] ^= 1
+		if _, err := c.unwrap(kek, wrapped); !errors.Is(err, milenage.ErrUnwrapFailed) {
+			t.Errorf("%s failed: unexpected error: %v", c.description+"/tampered", err)
+		}
+	}
+}
+
+func TestNewWithWrappedOPc(t *testing.T) {
+	c := cases[2]
+	e := c.expected.mil
+	kek := mustDecodeHex(t, "000102030405060708090a0b0c0d0e0f")
+
+	wrappedK, err := milenage.WrapKey(kek, e.K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrappedOPc, err := milenage.WrapKeyWithPadding(kek, e.OPc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := milenage.NewWithWrappedOPc(kek, wrappedK, wrappedOPc, e.RAND, 0xff9bb4d0b607, 0xb9b9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+	autn, err := m.GenerateAUTN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(autn, c.expected.autn); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, err := milenage.NewWithWrappedOPc(make([]byte, 16), wrappedK, wrappedOPc, e.RAND, 0, 0); !errors.Is(err, milenage.ErrUnwrapFailed) {
+		t.Errorf("unexpected error with wrong KEK: %v", err)
+	}
+}
@@ -9,17 +9,26 @@ and some helpers to be used during the authentication procedure.
 package milenage
 
 import (
-	"crypto/aes"
-	"crypto/hmac"
-	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
 )
 
 // Milenage is a set of parameters used/generated in MILENAGE algorithm.
+//
+// The methods on Milenage store the computed values into its own fields (e.g., F1 sets MACA,
+// and GenerateAUTS overwrites MACS and AKS with the values computed with AMF=0x0000), so the
+// results may depend on the order of the calls. The Verify methods are the exception; they
+// leave the computed values in m as they are. For the same reason, Milenage is not safe for
+// concurrent use; use one Milenage per goroutine, or use Compute and ComputeAUTS instead.
 type Milenage struct {
 	// K is a 128-bit subscriber key that is an input to the functions f1, f1*, f2, f3, f4, f5 and f5*.
+	//
+	// The AES key schedule of K is built at the first use and kept in m. To change K, set
+	// a new slice instead of modifying the one in use.
 	K []byte
+	// Kernel is the block cipher kernel that encrypts a block under K. If Kernel is set, it is
+	// used instead of K and K can be left nil, so that K does not need to be in the memory.
+	Kernel Kernel
 	// OP is a 128-bit Operator Variant Algorithm Configuration Field that is a component of the
 	// functions f1, f1*, f2, f3, f4, f5 and f5*.
 	OP []byte
@@ -52,6 +61,11 @@ type Milenage struct {
 
 	// RESStar or RES* is a 128-bit response that is used in 5G.
 	RESStar []byte
+
+	// softKernel is the SoftwareKernel built from softKernelK, which is the K used when
+	// Kernel is not set.
+	softKernel  *SoftwareKernel
+	softKernelK []byte
 }
 
 // New initializes a new MILENAGE algorithm.
@@ -112,6 +126,15 @@ func NewWithOPc(k, opc, rand []byte, sqn uint64, amf uint16) *Milenage {
 	return m
 }
 
+// NewWithKernel initializes a new MILENAGE algorithm using Kernel instead of K, with OPc.
+//
+// To use OP instead, compute OPc with ComputeOPcWithKernel beforehand.
+func NewWithKernel(kernel Kernel, opc, rand []byte, sqn uint64, amf uint16) *Milenage {
+	m := NewWithOPc(nil, opc, rand, sqn, amf)
+	m.Kernel = kernel
+	return m
+}
+
 // ComputeOPc is a helper that provides users to retrieve OPc value from
 // the K and OP given.
 func ComputeOPc(k, op []byte) ([]byte, error) {
@@ -122,6 +145,17 @@ func ComputeOPc(k, op []byte) ([]byte, error) {
 	return m.OPc, nil
 }
 
+// ComputeOPcWithKernel is a helper that provides users to retrieve OPc value from
+// the Kernel and OP given.
+func ComputeOPcWithKernel(kernel Kernel, op []byte) ([]byte, error) {
+	m := New(nil, op, make([]byte, 16), 0, 0)
+	m.Kernel = kernel
+	if err := m.computeOPc(); err != nil {
+		return nil, err
+	}
+	return m.OPc, nil
+}
+
 // ComputeAll fills all the fields in *Milenage struct.
 func (m *Milenage) ComputeAll() error {
 	if err := m.validateLength(); err != nil {
@@ -179,6 +213,20 @@ func (m *Milenage) F1Star(sqn, amf []byte) ([]byte, error) {
 // F2345 takes key K and random challenge RAND, and returns response RES,
 // confidentiality key CK, integrity key IK and anonymity key AK.
 func (m *Milenage) F2345() (res, ck, ik, ak []byte, err error) {
+	res, ck, ik, ak, err = m.f2345()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	m.RES = res
+	m.CK = ck
+	m.IK = ik
+	m.AK = ak
+	return res, ck, ik, ak, nil
+}
+
+// f2345 computes the same values as F2345 without storing them in m.
+func (m *Milenage) f2345() (res, ck, ik, ak []byte, err error) {
 	if err := m.validateLength(); err != nil {
 		return nil, nil, nil, nil, err
 	}
@@ -194,7 +242,7 @@ func (m *Milenage) F2345() (res, ck, ik, ak []byte, err error) {
 		rijndaelInput[i] = m.RAND[i] ^ m.OPc[i]
 	}
 
-	temp, err := encrypt(m.K, rijndaelInput)
+	temp, err := m.encrypt(rijndaelInput)
 	if err != nil {
 		return
 	}
@@ -206,7 +254,7 @@ func (m *Milenage) F2345() (res, ck, ik, ak []byte, err error) {
 	}
 	rijndaelInput[15] ^= 1
 
-	out, err := encrypt(m.K, rijndaelInput)
+	out, err := m.encrypt(rijndaelInput)
 	if err != nil {
 		return
 	}
@@ -221,7 +269,7 @@ func (m *Milenage) F2345() (res, ck, ik, ak []byte, err error) {
 	}
 	rijndaelInput[15] ^= 2
 
-	out, err = encrypt(m.K, rijndaelInput)
+	out, err = m.encrypt(rijndaelInput)
 	if err != nil {
 		return
 	}
@@ -235,22 +283,28 @@ func (m *Milenage) F2345() (res, ck, ik, ak []byte, err error) {
 	}
 	rijndaelInput[15] ^= 4
 
-	out, err = encrypt(m.K, rijndaelInput)
+	out, err = m.encrypt(rijndaelInput)
 	if err != nil {
 		return
 	}
 	ik = xor(out, m.OPc)
-
-	m.RES = res
-	m.CK = ck
-	m.IK = ik
-	m.AK = ak
 	return res, ck, ik, ak, nil
 }
 
 // F5Star is the anonymity key derivation function for the re-synchronisation message.
 // F5Star takes key K and random challenge RAND, and returns resynch anonymity key AK.
 func (m *Milenage) F5Star() (aks []byte, err error) {
+	aks, err = m.f5Star()
+	if err != nil {
+		return nil, err
+	}
+
+	m.AKS = aks
+	return aks, nil
+}
+
+// f5Star computes the same value as F5Star without storing it in m.
+func (m *Milenage) f5Star() (aks []byte, err error) {
 	if err := m.validateLength(); err != nil {
 		return nil, err
 	}
@@ -266,7 +320,7 @@ func (m *Milenage) F5Star() (aks []byte, err error) {
 		rijndaelInput[i] = m.RAND[i] ^ m.OPc[i]
 	}
 
-	tmp, err := encrypt(m.K, rijndaelInput)
+	tmp, err := m.encrypt(rijndaelInput)
 	if err != nil {
 		return
 	}
@@ -278,14 +332,12 @@ func (m *Milenage) F5Star() (aks []byte, err error) {
 	}
 	rijndaelInput[15] ^= 8
 
-	out, err := encrypt(m.K, rijndaelInput)
+	out, err := m.encrypt(rijndaelInput)
 	if err != nil {
 		return
 	}
 
-	aks = xor(out, m.OPc)[:6]
-	m.AKS = aks
-	return aks, nil
+	return xor(out, m.OPc)[:6], nil
 }
 
 // ComputeRESStar computes RESStar from serving network name, RAND and RES
@@ -298,42 +350,36 @@ func (m *Milenage) ComputeRESStar(mcc, mnc string) ([]byte, error) {
 		return nil, err
 	}
 
-	if len(mcc) != 3 {
-		return nil, fmt.Errorf("invalid MCC: %s", mcc)
-	}
-	if l := len(mnc); l == 2 {
-		mnc = "0" + mnc
-	} else if l != 3 {
-		return nil, fmt.Errorf("invalid MNC: %s", mnc)
+	p, err := NewPLMN(mcc, mnc)
+	if err != nil {
+		return nil, err
 	}
+	return m.ComputeRESStarWithSNN(p.ServingNetworkName())
+}
 
-	snn := []byte(fmt.Sprintf("5G:mnc%s.mcc%s.3gppnetwork.org", mnc, mcc))
-	if l := len(snn); l != 32 {
-		return nil, fmt.Errorf("failed to build SNN: %s", snn)
+// ComputeRESStarWithSNN computes RES* in the same way as ComputeRESStar, with the
+// serving network name given as it is. This accepts any forms of the serving network
+// name, e.g., the one of SNPN in the form "5G:mncXXX.mccXXX.3gppnetwork.org:NID"
+// (9.12.1, TS 24.501) or ServingNetworkNameNSWO.
+func (m *Milenage) ComputeRESStarWithSNN(snn string) ([]byte, error) {
+	if err := m.validateLength(); err != nil {
+		return nil, err
+	}
+	if err := ValidateServingNetworkName(snn); err != nil {
+		return nil, err
 	}
 
-	b := make([]byte, 63)
-	b[0] = 0x6b
-
-	copy(b[1:33], snn)
-	binary.BigEndian.PutUint16(b[33:35], uint16(len(snn)))
-
-	copy(b[35:51], m.RAND)
-	binary.BigEndian.PutUint16(b[51:53], uint16(len(m.RAND)))
-
-	copy(b[53:61], m.RES)
-	binary.BigEndian.PutUint16(b[61:63], uint16(len(m.RES)))
+	return m.resStar(m.CK, m.IK, m.RES, snn), nil
+}
 
+// resStar derives RES* from ck, ik and res given with RAND in m.
+func (m *Milenage) resStar(ck, ik, res []byte, snn string) []byte {
 	k := make([]byte, 32)
-	copy(k[0:16], m.CK)
-	copy(k[16:32], m.IK)
-	mac := hmac.New(sha256.New, k)
-	if _, err := mac.Write(b); err != nil {
-		return nil, fmt.Errorf("failed to compute RES*: %w", err)
-	}
+	copy(k[0:16], ck)
+	copy(k[16:32], ik)
 
-	out := mac.Sum(nil)
-	return out[len(out)-16:], nil
+	out := KDF(k, 0x6b, []byte(snn), m.RAND, res)
+	return out[len(out)-16:]
 }
 
 // GenerateAUTN generates AUTN uing the current values in Milenage
@@ -353,7 +399,8 @@ func (m *Milenage) GenerateAUTN() ([]byte, error) {
 // GenerateAUTS generates AUTS using the current values in Milenage
 // in the way described in 5.1.1.3, TS 33.105 and 6.3.3, TS 33.102.
 //
-// Note: MAC-S and AK-S are re-calculated with AMF=0x0000.
+// Note: MAC-S and AK-S are re-calculated with AMF=0x0000, and the values in MACS and
+// AKS are overwritten with them.
 func (m *Milenage) GenerateAUTS() ([]byte, error) {
 	if err := m.validateLength(); err != nil {
 		return nil, err
@@ -382,12 +429,10 @@ func (m *Milenage) GenerateAUTS() ([]byte, error) {
 func (m *Milenage) computeOPc() error {
 	m.OPc = make([]byte, 16)
 
-	block, err := aes.NewCipher(m.K)
+	cipherText, err := m.encrypt(m.OP)
 	if err != nil {
 		return err
 	}
-	cipherText := make([]byte, len(m.OP))
-	block.Encrypt(cipherText, m.OP)
 
 	bytes := xor(cipherText, m.OP)
 	for i, b := range bytes {
@@ -415,17 +460,46 @@ func xor(b1, b2 []byte) []byte {
 	return out
 }
 
-func encrypt(key, plain []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
+// encrypt encrypts a block with Kernel if set, or with K otherwise.
+func (m *Milenage) encrypt(plain []byte) ([]byte, error) {
+	if len(plain) != 16 {
+		return nil, fmt.Errorf("length of block to encrypt should be %d, got: %d", 16, len(plain))
+	}
+
+	kernel, err := m.kernel()
 	if err != nil {
 		return nil, err
 	}
 
-	encrypted := make([]byte, len(plain))
-	block.Encrypt(encrypted, plain)
+	encrypted := make([]byte, 16)
+	if err := kernel.EncryptBlock((*[16]byte)(encrypted), (*[16]byte)(plain)); err != nil {
+		return nil, err
+	}
 	return encrypted, nil
 }
 
+// kernel returns Kernel if set, or the SoftwareKernel built from K otherwise, which
+// is rebuilt only when K is replaced.
+func (m *Milenage) kernel() (Kernel, error) {
+	if m.Kernel != nil {
+		return m.Kernel, nil
+	}
+
+	if m.softKernel == nil || !sameSlice(m.K, m.softKernelK) {
+		k, err := NewSoftwareKernel(m.K)
+		if err != nil {
+			return nil, err
+		}
+		m.softKernel, m.softKernelK = k, m.K
+	}
+	return m.softKernel, nil
+}
+
+// sameSlice reports whether a and b refer to the same underlying bytes.
+func sameSlice(a, b []byte) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
 func (m *Milenage) f1base(sqn, amf []byte) ([]byte, error) {
 	if err := m.validateLength(); err != nil {
 		return nil, err
@@ -442,7 +516,7 @@ func (m *Milenage) f1base(sqn, amf []byte) ([]byte, error) {
 		rijndaelInput[i] = m.RAND[i] ^ m.OPc[i]
 	}
 
-	temp, err := encrypt(m.K, rijndaelInput)
+	temp, err := m.encrypt(rijndaelInput)
 	if err != nil {
 		return nil, err
 	}
@@ -468,7 +542,7 @@ func (m *Milenage) f1base(sqn, amf []byte) ([]byte, error) {
 		rijndaelInput[i] ^= temp[i]
 	}
 
-	out, err := encrypt(m.K, rijndaelInput)
+	out, err := m.encrypt(rijndaelInput)
 	if err != nil {
 		return nil, err
 	}
@@ -477,7 +551,7 @@ func (m *Milenage) f1base(sqn, amf []byte) ([]byte, error) {
 }
 
 func (m *Milenage) validateLength() error {
-	if len(m.K) != 16 {
+	if m.Kernel == nil && len(m.K) != 16 {
 		return fmt.Errorf("length of K should be %d, got: %d", 16, len(m.K))
 	}
 	if m.OP != nil && len(m.OP) != 16 {
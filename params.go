@@ -0,0 +1,150 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Params is a set of inputs of MILENAGE algorithm held in fixed-size arrays.
+//
+// Unlike Milenage, the length of each value is checked at compile time, and
+// Params (as well as Vector) is comparable so that it can be used as a map key.
+type Params struct {
+	// K is a 128-bit subscriber key.
+	K [16]byte
+	// OPc is a 128-bit value derived from OP and K.
+	OPc [16]byte
+	// RAND is a 128-bit random challenge.
+	RAND [16]byte
+	// SQN is a 48-bit sequence number.
+	SQN [6]byte
+	// AMF is a 16-bit authentication management field.
+	AMF [2]byte
+}
+
+// SQNFromUint64 returns SQN in [6]byte from uint64 value.
+// The upper 16 bits of sqn are ignored.
+func SQNFromUint64(sqn uint64) [6]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], sqn)
+
+	var s [6]byte
+	copy(s[:], b[2:])
+	return s
+}
+
+// SQNToUint64 returns SQN in uint64 from [6]byte value.
+func SQNToUint64(sqn [6]byte) uint64 {
+	var b [8]byte
+	copy(b[2:], sqn[:])
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// AMFFromUint16 returns AMF in [2]byte from uint16 value.
+func AMFFromUint16(amf uint16) [2]byte {
+	var a [2]byte
+	binary.BigEndian.PutUint16(a[:], amf)
+	return a
+}
+
+// Params returns the inputs in m as Params.
+//
+// If m is initialized with OP, OPc is computed and set in m as well.
+func (m *Milenage) Params() (Params, error) {
+	var p Params
+	if err := m.validateLength(); err != nil {
+		return p, err
+	}
+	if m.Kernel != nil {
+		return p, errors.New("K is not available with Kernel")
+	}
+
+	if m.OPc == nil {
+		if err := m.computeOPc(); err != nil {
+			return p, err
+		}
+	}
+
+	copy(p.K[:], m.K)
+	copy(p.OPc[:], m.OPc)
+	copy(p.RAND[:], m.RAND)
+	copy(p.SQN[:], m.SQN)
+	copy(p.AMF[:], m.AMF)
+	return p, nil
+}
+
+// Milenage returns a new Milenage initialized with the values in p.
+//
+// The returned Milenage does not share the underlying memory with p.
+func (p *Params) Milenage() *Milenage {
+	k, opc, rand := p.K, p.OPc, p.RAND
+	return NewWithOPc(k[:], opc[:], rand[:], SQNToUint64(p.SQN), binary.BigEndian.Uint16(p.AMF[:]))
+}
+
+// Vector returns the outputs in m as Vector.
+//
+// Be sure that the required calculation has done before calling this method,
+// as this method just copies the current values.
+func (m *Milenage) Vector() (Vector, error) {
+	var v Vector
+	if err := m.validateLength(); err != nil {
+		return v, err
+	}
+
+	autn, err := m.GenerateAUTN()
+	if err != nil {
+		return v, err
+	}
+
+	copy(v.MACA[:], m.MACA)
+	copy(v.MACS[:], m.MACS)
+	copy(v.RES[:], m.RES)
+	copy(v.CK[:], m.CK)
+	copy(v.IK[:], m.IK)
+	copy(v.AK[:], m.AK)
+	copy(v.AKS[:], m.AKS)
+	copy(v.AUTN[:], autn)
+	return v, nil
+}
+
+// ApplyTo sets the outputs in v to m.
+//
+// The fields in m do not share the underlying memory with v.
+func (v *Vector) ApplyTo(m *Milenage) {
+	w := *v
+	m.MACA = w.MACA[:]
+	m.MACS = w.MACS[:]
+	m.RES = w.RES[:]
+	m.CK = w.CK[:]
+	m.IK = w.IK[:]
+	m.AK = w.AK[:]
+	m.AKS = w.AKS[:]
+}
+
+// Compute computes all the values in Vector from p.
+//
+// Compute is a pure function; it does not mutate p or any other shared state,
+// so it is safe to be called from multiple goroutines at the same time.
+// MACS in the result is computed with p.AMF, not with the AMF=0x0000 used in AUTS.
+func Compute(p Params) Vector {
+	var v Vector
+	c := newCipher(&p.K, &p.OPc)
+	_ = c.Compute(&v, &p.RAND, &p.SQN, &p.AMF) // never fails with SoftwareKernel.
+	return v
+}
+
+// ComputeAUTS computes AUTS from p, using p.SQN as SQNMS, in the way described
+// in 6.3.3, TS 33.102. p.AMF is not used as MAC-S is computed with AMF=0x0000.
+//
+// ComputeAUTS is a pure function; it does not mutate p or any other shared state,
+// so it is safe to be called from multiple goroutines at the same time.
+func ComputeAUTS(p Params) [14]byte {
+	var auts [14]byte
+	c := newCipher(&p.K, &p.OPc)
+	_ = c.ComputeAUTS(&auts, &p.RAND, &p.SQN) // never fails with SoftwareKernel.
+	return auts
+}
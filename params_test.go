@@ -0,0 +1,169 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/wmnsk/milenage"
+)
+
+func TestParams(t *testing.T) {
+	for _, c := range cases {
+		e := c.expected.mil
+		sqn := milenage.SQNToUint64([6]byte(e.SQN))
+		amf := uint16(e.AMF[0])<<8 | uint16(e.AMF[1])
+		m := milenage.NewWithOPc(e.K, e.OPc, e.RAND, sqn, amf)
+		if e.OP != nil {
+			m = milenage.New(e.K, e.OP, e.RAND, sqn, amf)
+		}
+
+		p, err := m.Params()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := milenage.Params{
+			SQN: [6]byte(e.SQN),
+			AMF: milenage.AMFFromUint16(amf),
+		}
+		copy(want.K[:], e.K)
+		copy(want.OPc[:], e.OPc)
+		copy(want.RAND[:], e.RAND)
+		if diff := cmp.Diff(p, want); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+
+		// round trip.
+		got := p.Milenage()
+		if err := got.ComputeAll(); err != nil {
+			t.Fatal(err)
+		}
+		v, err := got.Vector()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(v.AUTN[:], c.expected.autn); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+
+		back := milenage.NewWithOPc(e.K, e.OPc, e.RAND, sqn, amf)
+		v.ApplyTo(back)
+		if diff := cmp.Diff(back, got, cmpopts.IgnoreUnexported(milenage.Milenage{})); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+
+		// must be usable as a map key.
+		seen := map[milenage.Params]milenage.Vector{p: v}
+		if _, ok := seen[p]; !ok {
+			t.Errorf("%s failed: Params not found in map", c.description)
+		}
+	}
+}
+
+func TestSQNFromUint64(t *testing.T) {
+	got := milenage.SQNFromUint64(0xffff_ff9bb4d0b607)
+	want := [6]byte{0xff, 0x9b, 0xb4, 0xd0, 0xb6, 0x07}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error(diff)
+	}
+
+	if n := milenage.SQNToUint64(got); n != 0xff9bb4d0b607 {
+		t.Errorf("unexpected SQN: %x", n)
+	}
+
+	if diff := cmp.Diff(milenage.AMFFromUint16(0xb9b9), [2]byte{0xb9, 0xb9}); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func paramsFrom(m *milenage.Milenage) milenage.Params {
+	p := milenage.Params{SQN: [6]byte(m.SQN), AMF: [2]byte(m.AMF)}
+	copy(p.K[:], m.K)
+	copy(p.OPc[:], m.OPc)
+	copy(p.RAND[:], m.RAND)
+	return p
+}
+
+func TestCompute(t *testing.T) {
+	for _, c := range cases {
+		e := c.expected.mil
+		v := milenage.Compute(paramsFrom(e))
+
+		if diff := cmp.Diff(v.AUTN[:], c.expected.autn); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/AUTN", diff)
+		}
+		if diff := cmp.Diff(v.RES[:], e.RES); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/RES", diff)
+		}
+		if diff := cmp.Diff(v.MACS[:], e.MACS); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/MACS", diff)
+		}
+		if diff := cmp.Diff(v.AKS[:], e.AKS); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/AKS", diff)
+		}
+
+		auts := milenage.ComputeAUTS(paramsFrom(e))
+		if diff := cmp.Diff(auts[:], c.expected.auts); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/AUTS", diff)
+		}
+	}
+}
+
+// TestComputeConcurrent is meant to be run with -race to ensure that
+// Compute and ComputeAUTS do not touch any shared state.
+func TestComputeConcurrent(t *testing.T) {
+	p := paramsFrom(cases[2].expected.mil)
+	want := milenage.Compute(p)
+	wantAUTS := milenage.ComputeAUTS(p)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := milenage.Compute(p); got != want {
+				t.Errorf("unexpected result: %+v", got)
+			}
+			if got := milenage.ComputeAUTS(p); got != wantAUTS {
+				t.Errorf("unexpected AUTS: %x", got)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestMilenagePerGoroutine is meant to be run with -race to ensure that
+// Milenage instances created from the same Params do not share memory.
+func TestMilenagePerGoroutine(t *testing.T) {
+	c := cases[2]
+	p := paramsFrom(c.expected.mil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := p.Milenage()
+			if err := m.ComputeAll(); err != nil {
+				t.Error(err)
+				return
+			}
+
+			// GenerateAUTS overwrites MACS with the value computed with AMF=0x0000.
+			if _, err := m.GenerateAUTS(); err != nil {
+				t.Error(err)
+				return
+			}
+			if diff := cmp.Diff(m.MACS, c.expected.mil.MACS); diff == "" {
+				t.Error("MACS is expected to be overwritten by GenerateAUTS")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,189 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ServingNetworkNameNSWO is the serving network name used in the authentication for
+// the non-seamless WLAN offload (NSWO) instead of the one built from PLMN (S.3, TS 33.501).
+const ServingNetworkNameNSWO = "5G:NSWO"
+
+// PLMN is the PLMN identity consisting of MCC and MNC in decimal digits.
+//
+// The number of digits in MNC is significant: MNC "01" and "001" are different PLMNs
+// in BCD, while they result in the same serving network name.
+type PLMN struct {
+	MCC string
+	MNC string
+}
+
+// NewPLMN creates a new PLMN with mcc and mnc validated.
+func NewPLMN(mcc, mnc string) (PLMN, error) {
+	p := PLMN{MCC: mcc, MNC: mnc}
+	if err := p.Validate(); err != nil {
+		return PLMN{}, err
+	}
+	return p, nil
+}
+
+// ParsePLMN decodes PLMN from 3 octets of BCD encoded by BCD.
+func ParsePLMN(b []byte) (PLMN, error) {
+	if len(b) != 3 {
+		return PLMN{}, fmt.Errorf("length of PLMN ID should be %d, got: %d", 3, len(b))
+	}
+
+	d := []byte{b[0] & 0x0f, b[0] >> 4, b[1] & 0x0f, b[2] & 0x0f, b[2] >> 4, b[1] >> 4}
+	if d[5] == 0x0f {
+		d = d[:5]
+	}
+	for i := range d {
+		if d[i] > 9 {
+			return PLMN{}, fmt.Errorf("invalid PLMN ID: %x", b)
+		}
+		d[i] += '0'
+	}
+	return PLMN{MCC: string(d[:3]), MNC: string(d[3:])}, nil
+}
+
+// Validate checks if MCC is 3 digits and MNC is 2 or 3 digits.
+func (p PLMN) Validate() error {
+	if len(p.MCC) != 3 || !isDigits(p.MCC) {
+		return fmt.Errorf("invalid MCC: %s", p.MCC)
+	}
+	if l := len(p.MNC); (l != 2 && l != 3) || !isDigits(p.MNC) {
+		return fmt.Errorf("invalid MNC: %s", p.MNC)
+	}
+	return nil
+}
+
+// String returns MCC and MNC concatenated, e.g., "00101".
+func (p PLMN) String() string {
+	return p.MCC + p.MNC
+}
+
+// BCD encodes p into 3 octets of BCD as defined in 9.3.3.5, TS 38.413, with the
+// filler 0xF in place of the third digit of a 2-digit MNC. This is the form used
+// as the serving network identity in the EPS KDFs (A.2, TS 33.401).
+//
+// p should be validated in advance.
+func (p PLMN) BCD() []byte {
+	mnc3 := byte(0x0f)
+	if len(p.MNC) == 3 {
+		mnc3 = p.MNC[2] - '0'
+	}
+	return []byte{
+		(p.MCC[1]-'0')<<4 | (p.MCC[0] - '0'),
+		mnc3<<4 | (p.MCC[2] - '0'),
+		(p.MNC[1]-'0')<<4 | (p.MNC[0] - '0'),
+	}
+}
+
+// ServingNetworkName returns the serving network name built from p as defined in
+// 6.1.1.4, TS 33.501, in the form "5G:mncXXX.mccXXX.3gppnetwork.org" with the SN Id
+// defined in 9.12.1, TS 24.501. The same form is used for the 3GPP access and the
+// untrusted/trusted non-3GPP access.
+func (p PLMN) ServingNetworkName() string {
+	mnc := p.MNC
+	if len(mnc) == 2 {
+		mnc = "0" + mnc
+	}
+	return "5G:mnc" + mnc + ".mcc" + p.MCC + ".3gppnetwork.org"
+}
+
+// ServingNetworkNameWithNID returns the serving network name of the SNPN identified
+// by p and nid, in the form "5G:mncXXX.mccXXX.3gppnetwork.org:NID" (9.12.1, TS 24.501).
+//
+// nid should be the NID in 11 hexadecimal digits (12.7, TS 23.003), and an error is
+// returned otherwise or if p is invalid.
+func (p PLMN) ServingNetworkNameWithNID(nid string) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	if !isNID(nid) {
+		return "", fmt.Errorf("invalid NID: %s", nid)
+	}
+	return p.ServingNetworkName() + ":" + nid, nil
+}
+
+// ParseServingNetworkName retrieves MCC and MNC from the serving network name
+// in the form "5G:mncXXX.mccXXX.3gppnetwork.org" (6.1.1.4, TS 33.501).
+//
+// MNC is always 3 digits as it is in the serving network name. The serving network
+// names which are not built from PLMN, e.g., ServingNetworkNameNSWO, and the ones of
+// SNPN are rejected. Use ParseServingNetworkNameWithNID to accept both PLMN and SNPN.
+func ParseServingNetworkName(snn string) (mcc, mnc string, err error) {
+	mcc, mnc, nid, err := ParseServingNetworkNameWithNID(snn)
+	if err != nil {
+		return "", "", err
+	}
+	if nid != "" {
+		return "", "", fmt.Errorf("unexpected NID in serving network name: %s", snn)
+	}
+	return mcc, mnc, nil
+}
+
+// ParseServingNetworkNameWithNID retrieves MCC, MNC and NID from the serving network
+// name in the form "5G:mncXXX.mccXXX.3gppnetwork.org:NID" (9.12.1, TS 24.501).
+// nid is empty if the serving network name is of PLMN, which has no ":NID" part.
+func ParseServingNetworkNameWithNID(snn string) (mcc, mnc, nid string, err error) {
+	s, ok := strings.CutPrefix(snn, "5G:mnc")
+	if ok {
+		var found bool
+		s, nid, found = strings.Cut(s, ":")
+		if found && !isNID(nid) {
+			return "", "", "", fmt.Errorf("invalid NID in serving network name: %s", snn)
+		}
+		s, ok = strings.CutSuffix(s, ".3gppnetwork.org")
+	}
+	if ok {
+		mnc, mcc, ok = strings.Cut(s, ".mcc")
+	}
+	if !ok || len(mnc) != 3 {
+		return "", "", "", fmt.Errorf("invalid serving network name: %s", snn)
+	}
+	if _, err := NewPLMN(mcc, mnc); err != nil {
+		return "", "", "", fmt.Errorf("invalid serving network name: %s: %w", snn, err)
+	}
+	return mcc, mnc, nid, nil
+}
+
+// ValidateServingNetworkName checks if snn can be used as P0 of the KDF in the way
+// ComputeRESStarWithSNN does. The form is not checked to accept any serving network
+// names, e.g., the one of SNPN with NID and ServingNetworkNameNSWO.
+func ValidateServingNetworkName(snn string) error {
+	if snn == "" {
+		return errors.New("serving network name should not be empty")
+	}
+	if len(snn) > 0xffff {
+		return fmt.Errorf("too long serving network name: %d", len(snn))
+	}
+	return nil
+}
+
+// isNID checks if s is 11 hexadecimal digits.
+func isNID(s string) bool {
+	if len(s) != 11 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
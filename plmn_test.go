@@ -0,0 +1,124 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+)
+
+func TestPLMN(t *testing.T) {
+	for _, c := range []struct {
+		description string
+		mcc, mnc    string
+		bcd         string
+		snn         string
+	}{
+		{"2-digit MNC", "001", "01", "00f110", "5G:mnc001.mcc001.3gppnetwork.org"},
+		{"3-digit MNC", "310", "410", "130014", "5G:mnc410.mcc310.3gppnetwork.org"},
+	} {
+		p, err := milenage.NewPLMN(c.mcc, c.mnc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(p.BCD(), mustDecodeHex(t, c.bcd)); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/BCD", diff)
+		}
+		if diff := cmp.Diff(p.ServingNetworkName(), c.snn); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/SNN", diff)
+		}
+
+		parsed, err := milenage.ParsePLMN(p.BCD())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(parsed, p); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description+"/ParsePLMN", diff)
+		}
+	}
+
+	for _, c := range [][2]string{{"01", "01"}, {"001", "1"}, {"001", "0001"}, {"00a", "01"}, {"001", "1a"}} {
+		if _, err := milenage.NewPLMN(c[0], c[1]); err == nil {
+			t.Errorf("expected error with %s/%s", c[0], c[1])
+		}
+	}
+	if _, err := milenage.ParsePLMN([]byte{0x00, 0xf1, 0x1a}); err == nil {
+		t.Error("expected error with invalid BCD")
+	}
+}
+
+func TestParseServingNetworkName(t *testing.T) {
+	mcc, mnc, err := milenage.ParseServingNetworkName("5G:mnc001.mcc001.3gppnetwork.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mcc != "001" || mnc != "001" {
+		t.Errorf("unexpected MCC/MNC: %s/%s", mcc, mnc)
+	}
+
+	for _, snn := range []string{
+		"5G:mnc01.mcc001.3gppnetwork.org",
+		"5G:mnc001.mcc001.example.org",
+		"mnc001.mcc001.3gppnetwork.org",
+		"5G:mnc0a1.mcc001.3gppnetwork.org",
+		milenage.ServingNetworkNameNSWO,
+	} {
+		if _, _, err := milenage.ParseServingNetworkName(snn); err == nil {
+			t.Errorf("expected error with %s", snn)
+		}
+	}
+}
+
+func TestParseServingNetworkNameWithNID(t *testing.T) {
+	p, err := milenage.NewPLMN("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snn, err := p.ServingNetworkNameWithNID("00000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(snn, "5G:mnc001.mcc001.3gppnetwork.org:00000000001"); diff != "" {
+		t.Errorf("ServingNetworkNameWithNID failed: \n%s", diff)
+	}
+
+	mcc, mnc, nid, err := milenage.ParseServingNetworkNameWithNID(snn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mcc != "001" || mnc != "001" || nid != "00000000001" {
+		t.Errorf("unexpected MCC/MNC/NID: %s/%s/%s", mcc, mnc, nid)
+	}
+	if _, _, err := milenage.ParseServingNetworkName(snn); err == nil {
+		t.Errorf("expected error with %s", snn)
+	}
+
+	_, _, nid, err = milenage.ParseServingNetworkNameWithNID(p.ServingNetworkName())
+	if err != nil || nid != "" {
+		t.Errorf("unexpected NID: %s, %v", nid, err)
+	}
+
+	for _, nid := range []string{"", "0000000001", "0000000000g", "00000000001:0"} {
+		if _, err := p.ServingNetworkNameWithNID(nid); err == nil {
+			t.Errorf("expected error with NID %q", nid)
+		}
+	}
+	if _, err := (milenage.PLMN{MCC: "001", MNC: "1"}).ServingNetworkNameWithNID("00000000001"); err == nil {
+		t.Error("expected error with invalid PLMN")
+	}
+
+	for _, snn := range []string{
+		"5G:mnc001.mcc001.3gppnetwork.org:",
+		"5G:mnc001.mcc001.3gppnetwork.org:0000000001",
+		"5G:mnc001.mcc001.3gppnetwork.org:0000000000g",
+		"5G:mnc001.mcc001.example.org:00000000001",
+	} {
+		if _, _, _, err := milenage.ParseServingNetworkNameWithNID(snn); err == nil {
+			t.Errorf("expected error with %s", snn)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// redacted is the placeholder printed instead of the secret values.
+const redacted = "[REDACTED]"
+
+// field is a name and value of a field in Milenage, used to format it.
+type field struct {
+	name   string
+	value  []byte
+	secret bool
+}
+
+func (m Milenage) fields() []field {
+	return []field{
+		{"K", m.K, true},
+		{"OP", m.OP, true},
+		{"OPc", m.OPc, true},
+		{"RAND", m.RAND, false},
+		{"SQN", m.SQN, false},
+		{"AMF", m.AMF, false},
+		{"MACA", m.MACA, false},
+		{"MACS", m.MACS, false},
+		{"RES", m.RES, true},
+		{"CK", m.CK, true},
+		{"IK", m.IK, true},
+		{"AK", m.AK, true},
+		{"AKS", m.AKS, true},
+		{"RESStar", m.RESStar, true},
+	}
+}
+
+func (f field) String() string {
+	if len(f.value) == 0 {
+		return "[]"
+	}
+	if f.secret {
+		return redacted
+	}
+	return hex.EncodeToString(f.value)
+}
+
+// Wipe overwrites the secret values in m with zeros, which are K, OP, OPc,
+// RES, CK, IK, AK, AKS and RESStar.
+//
+// Note that the slices given to New or NewWithOPc are overwritten as well,
+// as they are held in m without being copied.
+func (m *Milenage) Wipe() {
+	for _, f := range m.fields() {
+		if f.secret {
+			clear(f.value)
+		}
+	}
+	m.softKernel, m.softKernelK = nil, nil
+}
+
+// Format implements fmt.Formatter, which prints the values in hex string with
+// the secret values (the ones overwritten by Wipe) redacted, regardless of the verb.
+// The field names are printed with the plus flag (%+v).
+func (m Milenage) Format(s fmt.State, verb rune) {
+	fields := m.fields()
+	vals := make([]string, len(fields))
+	for i, f := range fields {
+		if s.Flag('+') || verb == 'v' && s.Flag('#') {
+			vals[i] = f.name + ":" + f.String()
+			continue
+		}
+		vals[i] = f.String()
+	}
+
+	fmt.Fprintf(s, "{%s}", strings.Join(vals, " "))
+}
+
+// LogValue implements slog.LogValuer, which logs the values in hex string with
+// the secret values (the ones overwritten by Wipe) redacted.
+func (m Milenage) LogValue() slog.Value {
+	fields := m.fields()
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.String(f.name, f.String())
+	}
+	return slog.GroupValue(attrs...)
+}
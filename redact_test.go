@@ -0,0 +1,96 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/wmnsk/milenage"
+)
+
+func newRedactTarget(t *testing.T) *milenage.Milenage {
+	t.Helper()
+
+	e := cases[2].expected.mil
+	m := milenage.New(
+		bytes.Clone(e.K), bytes.Clone(e.OP), bytes.Clone(e.RAND), 0xff9bb4d0b607, 0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ComputeRESStar("001", "01"); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func secretsIn(m *milenage.Milenage) map[string][]byte {
+	return map[string][]byte{
+		"K": m.K, "OP": m.OP, "OPc": m.OPc, "RES": m.RES, "CK": m.CK, "IK": m.IK, "AK": m.AK, "AKS": m.AKS,
+	}
+}
+
+func TestFormat(t *testing.T) {
+	m := newRedactTarget(t)
+	secrets := secretsIn(m)
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%x", "%X"} {
+		for _, arg := range []any{m, *m} {
+			out := fmt.Sprintf(format, arg)
+			for name, secret := range secrets {
+				if strings.Contains(strings.ToLower(out), hex.EncodeToString(secret)) {
+					t.Errorf("%s is not redacted with %s: %s", name, format, out)
+				}
+			}
+			if !strings.Contains(out, hex.EncodeToString(m.RAND)) {
+				t.Errorf("RAND is missing with %s: %s", format, out)
+			}
+		}
+	}
+
+	if out := fmt.Sprintf("%+v", m); !strings.Contains(out, "K:[REDACTED]") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestLogValue(t *testing.T) {
+	m := newRedactTarget(t)
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	logger.Info("test", "milenage", m)
+
+	for name, secret := range secretsIn(m) {
+		if strings.Contains(buf.String(), hex.EncodeToString(secret)) {
+			t.Errorf("%s is not redacted: %s", name, buf)
+		}
+	}
+	if !strings.Contains(buf.String(), `"RAND":"`+hex.EncodeToString(m.RAND)+`"`) {
+		t.Errorf("RAND is missing: %s", buf)
+	}
+}
+
+func TestWipe(t *testing.T) {
+	m := newRedactTarget(t)
+	k := m.K
+	m.Wipe()
+
+	for name, secret := range secretsIn(m) {
+		if !bytes.Equal(secret, make([]byte, len(secret))) {
+			t.Errorf("%s is not wiped: %x", name, secret)
+		}
+	}
+	if !bytes.Equal(k, make([]byte, 16)) {
+		t.Errorf("underlying K is not wiped: %x", k)
+	}
+	if bytes.Equal(m.RAND, make([]byte, 16)) {
+		t.Error("RAND should not be wiped")
+	}
+}
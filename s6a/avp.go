@@ -0,0 +1,60 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package s6a
+
+import (
+	"fmt"
+
+	"github.com/wmnsk/milenage/diameter"
+)
+
+// VendorID3GPP is the Vendor-Id of 3GPP.
+const VendorID3GPP = diameter.VendorID3GPP
+
+// AVP flags.
+const (
+	FlagVendor    = diameter.FlagVendor
+	FlagMandatory = diameter.FlagMandatory
+)
+
+// AVP is a Diameter AVP defined in 4.1, RFC 6733.
+//
+// It is an alias of diameter.AVP, which is shared with the other Diameter applications.
+type AVP = diameter.AVP
+
+// ParseAVP parses the first AVP in b, and returns it with the rest of b.
+// Data of the AVP returned refers to b.
+func ParseAVP(b []byte) (*AVP, []byte, error) {
+	return diameter.ParseAVP(b)
+}
+
+// ParseAVPs parses all the AVPs in b, e.g., the Data of a Grouped AVP.
+func ParseAVPs(b []byte) ([]*AVP, error) {
+	return diameter.ParseAVPs(b)
+}
+
+func newAVP(code uint32, data []byte) *AVP {
+	return diameter.NewAVP(code, data)
+}
+
+func group(code uint32, avps ...*AVP) *AVP {
+	return diameter.NewGroupedAVP(code, avps...)
+}
+
+func unsigned32(code, v uint32) *AVP {
+	return diameter.NewUnsigned32AVP(code, v)
+}
+
+func parseUnsigned32(a *AVP) (uint32, error) {
+	return a.Unsigned32()
+}
+
+// octets returns a copy of the Data of a, checking the length if n is not zero.
+func octets(a *AVP, n int) ([]byte, error) {
+	if n != 0 && len(a.Data) != n {
+		return nil, fmt.Errorf("invalid length of AVP %d: %d", a.Code, len(a.Data))
+	}
+	return append([]byte(nil), a.Data...), nil
+}
@@ -0,0 +1,355 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package s6a provides the encoding and decoding of the authentication information AVPs
+used in AIR/AIA over S6a/S6d (TS 29.272), to serialise the vectors computed by the milenage
+package without a full Diameter stack.
+
+Only the AVPs are handled here, and the Diameter header and the other AVPs in the
+messages are out of the scope of this package.
+*/
+package s6a
+
+import (
+	"fmt"
+
+	"github.com/wmnsk/milenage"
+)
+
+// AVP code definitions (TS 29.272 and TS 29.229).
+const (
+	CodeConfidentialityKey                    uint32 = 625
+	CodeIntegrityKey                          uint32 = 626
+	CodeRequestedEUTRANAuthenticationInfo     uint32 = 1408
+	CodeRequestedUTRANGERANAuthenticationInfo uint32 = 1409
+	CodeNumberOfRequestedVectors              uint32 = 1410
+	CodeResynchronizationInfo                 uint32 = 1411
+	CodeImmediateResponsePreferred            uint32 = 1412
+	CodeAuthenticationInfo                    uint32 = 1413
+	CodeEUTRANVector                          uint32 = 1414
+	CodeUTRANVector                           uint32 = 1415
+	CodeGERANVector                           uint32 = 1416
+	CodeItemNumber                            uint32 = 1419
+	CodeRAND                                  uint32 = 1447
+	CodeXRES                                  uint32 = 1448
+	CodeAUTN                                  uint32 = 1449
+	CodeKASME                                 uint32 = 1450
+	CodeKc                                    uint32 = 1453
+	CodeSRES                                  uint32 = 1454
+)
+
+// EUTRANVector is the E-UTRAN-Vector AVP (7.3.18, TS 29.272).
+type EUTRANVector struct {
+	// ItemNumber is the Item-Number AVP, which is omitted if zero.
+	ItemNumber uint32
+	RAND       []byte
+	XRES       []byte
+	AUTN       []byte
+	KASME      []byte
+}
+
+// NewEUTRANVector creates a new EUTRANVector from the values in m, with KASME
+// derived for the serving network identified by mcc and mnc.
+//
+// Note that this function should be called after F1 and F2345 are done.
+func NewEUTRANVector(m *milenage.Milenage, mcc, mnc string) (*EUTRANVector, error) {
+	autn, err := m.GenerateAUTN()
+	if err != nil {
+		return nil, err
+	}
+	kasme, err := m.ComputeKASME(mcc, mnc)
+	if err != nil {
+		return nil, err
+	}
+	return &EUTRANVector{RAND: clone(m.RAND), XRES: clone(m.RES), AUTN: autn, KASME: kasme}, nil
+}
+
+// AVP returns v as an AVP.
+func (v *EUTRANVector) AVP() *AVP {
+	return group(CodeEUTRANVector, withItemNumber(v.ItemNumber,
+		newAVP(CodeRAND, v.RAND),
+		newAVP(CodeXRES, v.XRES),
+		newAVP(CodeAUTN, v.AUTN),
+		newAVP(CodeKASME, v.KASME),
+	)...)
+}
+
+// UTRANVector is the UTRAN-Vector AVP (7.3.19, TS 29.272).
+type UTRANVector struct {
+	// ItemNumber is the Item-Number AVP, which is omitted if zero.
+	ItemNumber uint32
+	RAND       []byte
+	XRES       []byte
+	AUTN       []byte
+	CK         []byte
+	IK         []byte
+}
+
+// NewUTRANVector creates a new UTRANVector (quintet) from the values in m.
+//
+// Note that this function should be called after F1 and F2345 are done.
+func NewUTRANVector(m *milenage.Milenage) (*UTRANVector, error) {
+	autn, err := m.GenerateAUTN()
+	if err != nil {
+		return nil, err
+	}
+	return &UTRANVector{RAND: clone(m.RAND), XRES: clone(m.RES), AUTN: autn, CK: clone(m.CK), IK: clone(m.IK)}, nil
+}
+
+// AVP returns v as an AVP.
+func (v *UTRANVector) AVP() *AVP {
+	return group(CodeUTRANVector, withItemNumber(v.ItemNumber,
+		newAVP(CodeRAND, v.RAND),
+		newAVP(CodeXRES, v.XRES),
+		newAVP(CodeAUTN, v.AUTN),
+		newAVP(CodeConfidentialityKey, v.CK),
+		newAVP(CodeIntegrityKey, v.IK),
+	)...)
+}
+
+// GERANVector is the GERAN-Vector AVP (7.3.20, TS 29.272).
+type GERANVector struct {
+	// ItemNumber is the Item-Number AVP, which is omitted if zero.
+	ItemNumber uint32
+	RAND       []byte
+	SRES       []byte
+	Kc         []byte
+}
+
+// NewGERANVector creates a new GERANVector (triplet) from the values in m,
+// converted with the functions c2 and c3.
+//
+// Note that this function should be called after F2345 is done.
+func NewGERANVector(m *milenage.Milenage) (*GERANVector, error) {
+	sres, kc, err := m.GenerateTriplet()
+	if err != nil {
+		return nil, err
+	}
+	return &GERANVector{RAND: clone(m.RAND), SRES: sres, Kc: kc}, nil
+}
+
+// AVP returns v as an AVP.
+func (v *GERANVector) AVP() *AVP {
+	return group(CodeGERANVector, withItemNumber(v.ItemNumber,
+		newAVP(CodeRAND, v.RAND),
+		newAVP(CodeSRES, v.SRES),
+		newAVP(CodeKc, v.Kc),
+	)...)
+}
+
+// AuthenticationInfo is the Authentication-Info AVP (7.3.17, TS 29.272) sent in AIA.
+type AuthenticationInfo struct {
+	EUTRANVectors []*EUTRANVector
+	UTRANVectors  []*UTRANVector
+	GERANVectors  []*GERANVector
+}
+
+// AVP returns a as an AVP.
+func (a *AuthenticationInfo) AVP() *AVP {
+	avps := make([]*AVP, 0, len(a.EUTRANVectors)+len(a.UTRANVectors)+len(a.GERANVectors))
+	for _, v := range a.EUTRANVectors {
+		avps = append(avps, v.AVP())
+	}
+	for _, v := range a.UTRANVectors {
+		avps = append(avps, v.AVP())
+	}
+	for _, v := range a.GERANVectors {
+		avps = append(avps, v.AVP())
+	}
+	return group(CodeAuthenticationInfo, avps...)
+}
+
+// Marshal returns the byte sequence of a as an AVP.
+func (a *AuthenticationInfo) Marshal() []byte {
+	return a.AVP().Marshal()
+}
+
+// ParseAuthenticationInfo decodes the Authentication-Info AVP in b.
+// Unknown AVPs inside are ignored.
+func ParseAuthenticationInfo(b []byte) (*AuthenticationInfo, error) {
+	avps, err := parseGrouped(b, CodeAuthenticationInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &AuthenticationInfo{}
+	for _, avp := range avps {
+		switch avp.Code {
+		case CodeEUTRANVector:
+			v := &EUTRANVector{}
+			if err := parseVector(avp, &v.ItemNumber, map[uint32]*[]byte{
+				CodeRAND: &v.RAND, CodeXRES: &v.XRES, CodeAUTN: &v.AUTN, CodeKASME: &v.KASME,
+			}); err != nil {
+				return nil, err
+			}
+			a.EUTRANVectors = append(a.EUTRANVectors, v)
+		case CodeUTRANVector:
+			v := &UTRANVector{}
+			if err := parseVector(avp, &v.ItemNumber, map[uint32]*[]byte{
+				CodeRAND: &v.RAND, CodeXRES: &v.XRES, CodeAUTN: &v.AUTN,
+				CodeConfidentialityKey: &v.CK, CodeIntegrityKey: &v.IK,
+			}); err != nil {
+				return nil, err
+			}
+			a.UTRANVectors = append(a.UTRANVectors, v)
+		case CodeGERANVector:
+			v := &GERANVector{}
+			if err := parseVector(avp, &v.ItemNumber, map[uint32]*[]byte{
+				CodeRAND: &v.RAND, CodeSRES: &v.SRES, CodeKc: &v.Kc,
+			}); err != nil {
+				return nil, err
+			}
+			a.GERANVectors = append(a.GERANVectors, v)
+		}
+	}
+	return a, nil
+}
+
+// RequestedAuthenticationInfo is the Requested-EUTRAN-Authentication-Info AVP (7.3.11, TS 29.272)
+// or the Requested-UTRAN-GERAN-Authentication-Info AVP (7.3.12, TS 29.272) sent in AIR,
+// which have the same structure.
+type RequestedAuthenticationInfo struct {
+	// Code is either CodeRequestedEUTRANAuthenticationInfo or CodeRequestedUTRANGERANAuthenticationInfo.
+	Code uint32
+	// NumberOfRequestedVectors is the Number-Of-Requested-Vectors AVP, which is omitted if zero.
+	NumberOfRequestedVectors uint32
+	// ImmediateResponsePreferred is true if the Immediate-Response-Preferred AVP is present.
+	ImmediateResponsePreferred bool
+	// ResynchronizationInfo is the Re-Synchronization-Info AVP, which is the concatenation
+	// of RAND and AUTS. Use RANDAndAUTS to split it.
+	ResynchronizationInfo []byte
+}
+
+// NewResynchronizationInfo builds the value of Re-Synchronization-Info AVP from RAND and AUTS.
+func NewResynchronizationInfo(rand, auts []byte) ([]byte, error) {
+	if len(rand) != 16 {
+		return nil, fmt.Errorf("length of RAND should be %d, got: %d", 16, len(rand))
+	}
+	if len(auts) != 14 {
+		return nil, fmt.Errorf("length of AUTS should be %d, got: %d", 14, len(auts))
+	}
+	return append(clone(rand), auts...), nil
+}
+
+// RANDAndAUTS splits Re-Synchronization-Info into RAND and AUTS (7.3.15, TS 29.272).
+// RAND and AUTS can be used with milenage.Milenage.VerifyAUTS to re-synchronise SQN.
+//
+// ok is false if r does not have Re-Synchronization-Info.
+func (r *RequestedAuthenticationInfo) RANDAndAUTS() (rand, auts []byte, ok bool, err error) {
+	if r.ResynchronizationInfo == nil {
+		return nil, nil, false, nil
+	}
+	if l := len(r.ResynchronizationInfo); l != 30 {
+		return nil, nil, false, fmt.Errorf("length of Re-Synchronization-Info should be %d, got: %d", 30, l)
+	}
+	return clone(r.ResynchronizationInfo[:16]), clone(r.ResynchronizationInfo[16:]), true, nil
+}
+
+// AVP returns r as an AVP.
+func (r *RequestedAuthenticationInfo) AVP() *AVP {
+	var avps []*AVP
+	if r.NumberOfRequestedVectors != 0 {
+		avps = append(avps, unsigned32(CodeNumberOfRequestedVectors, r.NumberOfRequestedVectors))
+	}
+	if r.ImmediateResponsePreferred {
+		avps = append(avps, unsigned32(CodeImmediateResponsePreferred, 0))
+	}
+	if r.ResynchronizationInfo != nil {
+		avps = append(avps, newAVP(CodeResynchronizationInfo, r.ResynchronizationInfo))
+	}
+	return group(r.Code, avps...)
+}
+
+// Marshal returns the byte sequence of r as an AVP.
+func (r *RequestedAuthenticationInfo) Marshal() []byte {
+	return r.AVP().Marshal()
+}
+
+// ParseRequestedAuthenticationInfo decodes the Requested-EUTRAN-Authentication-Info AVP or
+// the Requested-UTRAN-GERAN-Authentication-Info AVP in b.
+func ParseRequestedAuthenticationInfo(b []byte) (*RequestedAuthenticationInfo, error) {
+	a, _, err := ParseAVP(b)
+	if err != nil {
+		return nil, err
+	}
+	if a.Code != CodeRequestedEUTRANAuthenticationInfo && a.Code != CodeRequestedUTRANGERANAuthenticationInfo {
+		return nil, fmt.Errorf("unexpected AVP code: %d", a.Code)
+	}
+	avps, err := ParseAVPs(a.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RequestedAuthenticationInfo{Code: a.Code}
+	for _, avp := range avps {
+		switch avp.Code {
+		case CodeNumberOfRequestedVectors:
+			if r.NumberOfRequestedVectors, err = parseUnsigned32(avp); err != nil {
+				return nil, err
+			}
+		case CodeImmediateResponsePreferred:
+			r.ImmediateResponsePreferred = true
+		case CodeResynchronizationInfo:
+			if r.ResynchronizationInfo, err = octets(avp, 30); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+func parseGrouped(b []byte, code uint32) ([]*AVP, error) {
+	a, _, err := ParseAVP(b)
+	if err != nil {
+		return nil, err
+	}
+	if a.Code != code {
+		return nil, fmt.Errorf("unexpected AVP code: %d", a.Code)
+	}
+	return ParseAVPs(a.Data)
+}
+
+// parseVector decodes the AVPs in the vector a into the fields, all of which are mandatory.
+func parseVector(a *AVP, itemNumber *uint32, fields map[uint32]*[]byte) error {
+	avps, err := ParseAVPs(a.Data)
+	if err != nil {
+		return err
+	}
+
+	for _, avp := range avps {
+		if avp.Code == CodeItemNumber {
+			if *itemNumber, err = parseUnsigned32(avp); err != nil {
+				return err
+			}
+			continue
+		}
+		if f, ok := fields[avp.Code]; ok {
+			if *f, err = octets(avp, 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	for code, f := range fields {
+		if *f == nil {
+			return fmt.Errorf("missing AVP %d in AVP %d", code, a.Code)
+		}
+	}
+	return nil
+}
+
+func withItemNumber(n uint32, avps ...*AVP) []*AVP {
+	if n == 0 {
+		return avps
+	}
+	return append([]*AVP{unsigned32(CodeItemNumber, n)}, avps...)
+}
+
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
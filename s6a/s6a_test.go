@@ -0,0 +1,204 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package s6a_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/s6a"
+)
+
+func TestAVP(t *testing.T) {
+	a := &s6a.AVP{Code: 1, Flags: s6a.FlagMandatory, Data: []byte{0x01, 0x02, 0x03}}
+	b := a.Marshal()
+	if diff := cmp.Diff(b, []byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x00, 0x00, 0x0b, 0x01, 0x02, 0x03, 0x00}); diff != "" {
+		t.Error(diff)
+	}
+
+	got, rest, err := s6a.ParseAVP(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, a); diff != "" {
+		t.Error(diff)
+	}
+	if len(rest) != 0 {
+		t.Errorf("unexpected rest: %x", rest)
+	}
+
+	for _, b := range [][]byte{
+		{0x00, 0x00, 0x00, 0x01, 0x40, 0x00, 0x00},
+		{0x00, 0x00, 0x00, 0x01, 0x40, 0x00, 0x00, 0x10, 0x01, 0x02, 0x03},
+		{0x00, 0x00, 0x00, 0x01, 0xc0, 0x00, 0x00, 0x0b, 0x00, 0x00, 0x28},
+	} {
+		if _, _, err := s6a.ParseAVP(b); err == nil {
+			t.Errorf("expected error with %x", b)
+		}
+	}
+}
+
+func TestAuthenticationInfo(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	ev, err := s6a.NewEUTRANVector(m, "001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev.ItemNumber = 1
+	uv, err := s6a.NewUTRANVector(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gv, err := s6a.NewGERANVector(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kasme, err := m.ComputeKASME("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(ev, &s6a.EUTRANVector{
+		ItemNumber: 1,
+		RAND:       m.RAND,
+		XRES:       m.RES,
+		AUTN:       []byte{0x55, 0xf3, 0x28, 0xb4, 0x35, 0x77, 0xb9, 0xb9, 0x4a, 0x9f, 0xfa, 0xc3, 0x54, 0xdf, 0xaf, 0xb3},
+		KASME:      kasme,
+	}); diff != "" {
+		t.Error(diff)
+	}
+
+	ai := &s6a.AuthenticationInfo{
+		EUTRANVectors: []*s6a.EUTRANVector{ev},
+		UTRANVectors:  []*s6a.UTRANVector{uv},
+		GERANVectors:  []*s6a.GERANVector{gv},
+	}
+	b := ai.Marshal()
+
+	// E-UTRAN-Vector: 12 + Item-Number 16 + RAND 28 + XRES 20 + AUTN 28 + KASME 44.
+	// UTRAN-Vector: 12 + RAND 28 + XRES 20 + AUTN 28 + CK 28 + IK 28.
+	// GERAN-Vector: 12 + RAND 28 + SRES 16 + Kc 20.
+	if l := len(b); l != 12+148+144+76 {
+		t.Errorf("unexpected length: %d", l)
+	}
+	if diff := cmp.Diff(b[:12], []byte{0x00, 0x00, 0x05, 0x85, 0xc0, 0x00, 0x01, 0x7c, 0x00, 0x00, 0x28, 0xaf}); diff != "" {
+		t.Error(diff)
+	}
+
+	got, err := s6a.ParseAuthenticationInfo(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, ai); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParseAuthenticationInfoError(t *testing.T) {
+	// E-UTRAN-Vector without KASME.
+	ev := &s6a.EUTRANVector{RAND: make([]byte, 16), XRES: make([]byte, 8), AUTN: make([]byte, 16)}
+	b := (&s6a.AuthenticationInfo{EUTRANVectors: []*s6a.EUTRANVector{ev}}).Marshal()
+	if _, err := s6a.ParseAuthenticationInfo(b); err == nil {
+		t.Error("expected error with missing KASME")
+	}
+
+	r := &s6a.RequestedAuthenticationInfo{Code: s6a.CodeRequestedEUTRANAuthenticationInfo}
+	if _, err := s6a.ParseAuthenticationInfo(r.Marshal()); err == nil {
+		t.Error("expected error with unexpected AVP code")
+	}
+}
+
+func TestRequestedAuthenticationInfo(t *testing.T) {
+	r := &s6a.RequestedAuthenticationInfo{
+		Code:                       s6a.CodeRequestedEUTRANAuthenticationInfo,
+		NumberOfRequestedVectors:   3,
+		ImmediateResponsePreferred: true,
+	}
+	b := r.Marshal()
+	want := []byte{
+		0x00, 0x00, 0x05, 0x80, 0xc0, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x28, 0xaf, 0x00, 0x00, 0x05, 0x82,
+		0xc0, 0x00, 0x00, 0x10, 0x00, 0x00, 0x28, 0xaf, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x05, 0x84,
+		0xc0, 0x00, 0x00, 0x10, 0x00, 0x00, 0x28, 0xaf, 0x00, 0x00, 0x00, 0x00,
+	}
+	if diff := cmp.Diff(b, want); diff != "" {
+		t.Error(diff)
+	}
+
+	got, err := s6a.ParseRequestedAuthenticationInfo(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, r); diff != "" {
+		t.Error(diff)
+	}
+	if _, _, ok, err := got.RANDAndAUTS(); ok || err != nil {
+		t.Errorf("unexpected Re-Synchronization-Info: %v, %v", ok, err)
+	}
+}
+
+func TestResynchronizationInfo(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// UE has SQNMS=0x1000 and rejects the AUTN received.
+	ue := milenage.NewWithOPc(m.K, m.OPc, m.RAND, 0x1000, 0)
+	auts, err := ue.GenerateAUTS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := s6a.NewResynchronizationInfo(m.RAND, auts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &s6a.RequestedAuthenticationInfo{
+		Code:                     s6a.CodeRequestedUTRANGERANAuthenticationInfo,
+		NumberOfRequestedVectors: 1,
+		ResynchronizationInfo:    info,
+	}
+
+	got, err := s6a.ParseRequestedAuthenticationInfo(r.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rand, gotAUTS, ok, err := got.RANDAndAUTS()
+	if err != nil || !ok {
+		t.Fatalf("failed to get RAND and AUTS: %v, %v", ok, err)
+	}
+	if diff := cmp.Diff(rand, m.RAND); diff != "" {
+		t.Error(diff)
+	}
+
+	hss := milenage.NewWithOPc(m.K, m.OPc, rand, 0, 0)
+	sqnMS, ok, err := hss.VerifyAUTS(gotAUTS)
+	if err != nil || !ok {
+		t.Fatalf("AUTS verification failed: %v, %v", ok, err)
+	}
+	if sqn := milenage.SQNToUint64([6]byte(sqnMS)); sqn != 0x1000 {
+		t.Errorf("unexpected SQNMS: %x", sqn)
+	}
+
+	if _, err := s6a.NewResynchronizationInfo(m.RAND, auts[:13]); err == nil {
+		t.Error("expected error with invalid AUTS")
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package simout provides a parser for the output files (.out) delivered by SIM manufacturers
+together with a batch of SIM cards, which contain the per-card values such as IMSI, ICCID, Ki and OPc.
+
+The file is expected to consist of the comment lines starting with "*", the header lines in
+"name: value" format, the "var_Out:" line that lists the column names separated by "/", and
+the data lines that have the values separated by white spaces in the same order as the columns.
+*/
+package simout
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wmnsk/milenage"
+)
+
+// ErrOPcMismatch is returned by VerifyOPc when OPc in a record does not match
+// the one computed from Ki and OP.
+var ErrOPcMismatch = errors.New("OPc mismatch")
+
+// File is the contents of a SIM vendor output file.
+type File struct {
+	// Header is the set of "name: value" lines before the columns definition.
+	Header map[string]string
+	// Columns is the list of column names in the "var_Out:" line.
+	Columns []string
+	// Records is the list of records, each of which corresponds to a data line.
+	Records []*Record
+}
+
+// Record is a set of values for a SIM card.
+type Record struct {
+	ICCID string
+	IMSI  string
+	Ki    []byte
+	OPc   []byte
+	ADM1  string
+	PIN1  string
+	PUK1  string
+	PIN2  string
+	PUK2  string
+
+	// Fields holds all the values in the data line as is, keyed by the column names.
+	Fields map[string]string
+}
+
+// Parse parses a SIM vendor output file read from r.
+//
+// Ki and OPc are decoded from hex string but not decrypted. Call Decrypt if they
+// are encrypted with a transport key.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{Header: map[string]string{}}
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "*") {
+			continue
+		}
+
+		if f.Columns == nil {
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("line %d: unexpected line before var_Out: %s", n, line)
+			}
+			name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+
+			if strings.EqualFold(name, "var_Out") {
+				for _, c := range strings.Split(value, "/") {
+					f.Columns = append(f.Columns, strings.TrimSpace(c))
+				}
+				continue
+			}
+			f.Header[name] = value
+			continue
+		}
+
+		rec, err := parseRecord(f.Columns, strings.Fields(line))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		f.Records = append(f.Records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if f.Columns == nil {
+		return nil, errors.New("var_Out not found")
+	}
+	return f, nil
+}
+
+func parseRecord(columns, values []string) (*Record, error) {
+	if len(values) != len(columns) {
+		return nil, fmt.Errorf("number of values should be %d, got: %d", len(columns), len(values))
+	}
+
+	r := &Record{Fields: make(map[string]string, len(columns))}
+	for i, c := range columns {
+		v := values[i]
+		r.Fields[c] = v
+
+		var err error
+		switch strings.ToUpper(c) {
+		case "ICCID":
+			r.ICCID = v
+		case "IMSI":
+			r.IMSI = v
+		case "KI":
+			r.Ki, err = hex.DecodeString(v)
+		case "OPC":
+			r.OPc, err = hex.DecodeString(v)
+		case "ADM1", "ADM":
+			r.ADM1 = v
+		case "PIN1":
+			r.PIN1 = v
+		case "PUK1":
+			r.PUK1 = v
+		case "PIN2":
+			r.PIN2 = v
+		case "PUK2":
+			r.PUK2 = v
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", c, err)
+		}
+	}
+	return r, nil
+}
+
+// Decrypt decrypts Ki and OPc in all the records with the transport key.
+// See Record.Decrypt for details. All the records are validated before decryption,
+// so none of them are modified if an error is returned.
+func (f *File) Decrypt(transportKey cipher.Block) error {
+	for _, r := range f.Records {
+		if err := r.validateLength(transportKey.BlockSize()); err != nil {
+			return fmt.Errorf("IMSI %s: %w", r.IMSI, err)
+		}
+	}
+	for _, r := range f.Records {
+		r.decrypt(transportKey)
+	}
+	return nil
+}
+
+// VerifyOPc verifies OPc in all the records with OP.
+// See Record.VerifyOPc for details.
+func (f *File) VerifyOPc(op []byte) error {
+	for _, r := range f.Records {
+		if err := r.VerifyOPc(op); err != nil {
+			return fmt.Errorf("IMSI %s: %w", r.IMSI, err)
+		}
+	}
+	return nil
+}
+
+// Decrypt decrypts Ki and OPc in r in ECB mode with the transport key, e.g., the
+// one created by aes.NewCipher or des.NewTripleDESCipher.
+//
+// Decrypt overwrites the values in r, so it should be called only once on success.
+// If an error is returned, r is left unmodified.
+func (r *Record) Decrypt(transportKey cipher.Block) error {
+	if err := r.validateLength(transportKey.BlockSize()); err != nil {
+		return err
+	}
+	r.decrypt(transportKey)
+	return nil
+}
+
+// validateLength checks if the lengths of Ki and OPc are multiples of the block size.
+func (r *Record) validateLength(bs int) error {
+	for _, v := range []struct {
+		name  string
+		value []byte
+	}{{"Ki", r.Ki}, {"OPc", r.OPc}} {
+		if len(v.value)%bs != 0 {
+			return fmt.Errorf("length of %s should be a multiple of %d, got: %d", v.name, bs, len(v.value))
+		}
+	}
+	return nil
+}
+
+// decrypt decrypts Ki and OPc in place, whose lengths should be validated in advance.
+func (r *Record) decrypt(transportKey cipher.Block) {
+	bs := transportKey.BlockSize()
+	for _, v := range [][]byte{r.Ki, r.OPc} {
+		for i := 0; i < len(v); i += bs {
+			transportKey.Decrypt(v[i:i+bs], v[i:i+bs])
+		}
+	}
+}
+
+// VerifyOPc computes OPc from Ki and OP with milenage.ComputeOPc and verifies
+// if it matches OPc in r. ErrOPcMismatch is returned if not.
+func (r *Record) VerifyOPc(op []byte) error {
+	opc, err := milenage.ComputeOPc(r.Ki, op)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(opc, r.OPc) != 1 {
+		return ErrOPcMismatch
+	}
+	return nil
+}
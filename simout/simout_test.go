@@ -0,0 +1,161 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package simout_test
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage/simout"
+)
+
+var (
+	ki, _  = hex.DecodeString("465b5ce8b199b49faa5f0a2ee238a6bc")
+	op, _  = hex.DecodeString("cdc202d5123e20f62b6d676ac72cb318")
+	opc, _ = hex.DecodeString("cd63cb71954a9f4e48a5994e37a02baf")
+	tk, _  = hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+)
+
+const plainFile = `*
+* HEADER DESCRIPTION
+*************************
+Customer   : Example
+Quantity   : 2
+Type       : USIM
+*
+* OUTPUT VARIABLES
+*************************
+var_Out: ICCID/IMSI/PIN1/PUK1/PIN2/PUK2/Ki/OPC/ADM1
+8988211000000000011 001010000000001 1234 11111111 5678 22222222 465b5ce8b199b49faa5f0a2ee238a6bc cd63cb71954a9f4e48a5994e37a02baf 12345678
+8988211000000000029 001010000000002 4321 33333333 8765 44444444 465b5ce8b199b49faa5f0a2ee238a6bc cd63cb71954a9f4e48a5994e37a02baf 87654321
+`
+
+func TestParse(t *testing.T) {
+	f, err := simout.Parse(strings.NewReader(plainFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(f.Header, map[string]string{"Customer": "Example", "Quantity": "2", "Type": "USIM"}); diff != "" {
+		t.Error(diff)
+	}
+	if len(f.Records) != 2 {
+		t.Fatalf("unexpected number of records: %d", len(f.Records))
+	}
+
+	want := &simout.Record{
+		ICCID: "8988211000000000011",
+		IMSI:  "001010000000001",
+		Ki:    ki,
+		OPc:   opc,
+		ADM1:  "12345678",
+		PIN1:  "1234",
+		PUK1:  "11111111",
+		PIN2:  "5678",
+		PUK2:  "22222222",
+		Fields: map[string]string{
+			"ICCID": "8988211000000000011",
+			"IMSI":  "001010000000001",
+			"PIN1":  "1234",
+			"PUK1":  "11111111",
+			"PIN2":  "5678",
+			"PUK2":  "22222222",
+			"Ki":    "465b5ce8b199b49faa5f0a2ee238a6bc",
+			"OPC":   "cd63cb71954a9f4e48a5994e37a02baf",
+			"ADM1":  "12345678",
+		},
+	}
+	if diff := cmp.Diff(f.Records[0], want); diff != "" {
+		t.Error(diff)
+	}
+
+	if err := f.VerifyOPc(op); err != nil {
+		t.Error(err)
+	}
+	if err := f.VerifyOPc(make([]byte, 16)); !errors.Is(err, simout.ErrOPcMismatch) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	block, err := aes.NewCipher(tk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encKi, encOPc := make([]byte, 16), make([]byte, 16)
+	block.Encrypt(encKi, ki)
+	block.Encrypt(encOPc, opc)
+
+	file := "var_Out: IMSI/Ki/OPC\n001010000000001 " + hex.EncodeToString(encKi) + " " + hex.EncodeToString(encOPc) + "\n"
+	f, err := simout.Parse(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.VerifyOPc(op); !errors.Is(err, simout.ErrOPcMismatch) {
+		t.Errorf("unexpected error before decryption: %v", err)
+	}
+	if err := f.Decrypt(block); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(f.Records[0].Ki, ki); diff != "" {
+		t.Error(diff)
+	}
+	if err := f.VerifyOPc(op); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecryptError(t *testing.T) {
+	block, err := aes.NewCipher(tk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second record has OPc of wrong length, and no records should be modified.
+	file := "var_Out: IMSI/Ki/OPC\n" +
+		"001010000000001 " + hex.EncodeToString(ki) + " " + hex.EncodeToString(opc) + "\n" +
+		"001010000000002 " + hex.EncodeToString(ki) + " " + hex.EncodeToString(opc[:15]) + "\n"
+	f, err := simout.Parse(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Decrypt(block); err == nil {
+		t.Fatal("expected error")
+	}
+	for _, r := range f.Records {
+		if diff := cmp.Diff(r.Ki, ki); diff != "" {
+			t.Errorf("IMSI %s: \n%s", r.IMSI, diff)
+		}
+	}
+	if err := f.Records[1].Decrypt(block); err == nil {
+		t.Fatal("expected error")
+	}
+	if diff := cmp.Diff(f.Records[1].Ki, ki); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, c := range []struct {
+		description string
+		file        string
+	}{
+		{"no var_Out", "Customer: Example\n"},
+		{"missing value", "var_Out: IMSI/Ki\n001010000000001\n"},
+		{"invalid Ki", "var_Out: IMSI/Ki\n001010000000001 xyz\n"},
+		{"garbage header", "hello\nvar_Out: IMSI\n001010000000001\n"},
+	} {
+		if _, err := simout.Parse(strings.NewReader(c.file)); err == nil {
+			t.Errorf("%s: expected error", c.description)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package suci
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrMACFailure is returned when the MAC tag in the scheme output does not match.
+var ErrMACFailure = errors.New("MAC tag mismatch in SUCI scheme output")
+
+// length definitions of the ECIES profiles (C.3.4, TS 33.501).
+const (
+	encKeyLen = 16
+	icbLen    = 16
+	macKeyLen = 32
+	macTagLen = 8
+)
+
+// curve returns the curve used in the protection scheme.
+func curve(scheme uint8) (ecdh.Curve, error) {
+	switch scheme {
+	case ProtectionSchemeProfileA:
+		return ecdh.X25519(), nil
+	case ProtectionSchemeProfileB:
+		return ecdh.P256(), nil
+	default:
+		return nil, fmt.Errorf("unsupported protection scheme: %d", scheme)
+	}
+}
+
+// ephemeralKeyLen returns the length of the ephemeral public key in the scheme output.
+func ephemeralKeyLen(scheme uint8) int {
+	if scheme == ProtectionSchemeProfileB {
+		return 33 // compressed point.
+	}
+	return 32
+}
+
+// encrypt conceals the scheme input with the home network public key and the ephemeral
+// private key, and returns the scheme output (C.3.2, TS 33.501).
+func encrypt(scheme uint8, hnPub *ecdh.PublicKey, eph *ecdh.PrivateKey, input []byte) ([]byte, error) {
+	z, err := eph.ECDH(hnPub)
+	if err != nil {
+		return nil, err
+	}
+	r := marshalPublicKey(scheme, eph.PublicKey())
+	encKey, icb, macKey := deriveKeys(z, r)
+
+	out := make([]byte, 0, len(r)+len(input)+macTagLen)
+	out = append(out, r...)
+	ct, err := ctr(encKey, icb, input)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, ct...)
+	return append(out, macTag(macKey, ct)...), nil
+}
+
+// decrypt de-conceals the scheme output with the home network private key (C.3.3, TS 33.501).
+func decrypt(scheme uint8, hnPriv *ecdh.PrivateKey, output []byte) ([]byte, error) {
+	n := ephemeralKeyLen(scheme)
+	if len(output) < n+macTagLen {
+		return nil, fmt.Errorf("too short scheme output: %d", len(output))
+	}
+	r, ct, tag := output[:n], output[n:len(output)-macTagLen], output[len(output)-macTagLen:]
+
+	pub, err := unmarshalPublicKey(scheme, r)
+	if err != nil {
+		return nil, err
+	}
+	z, err := hnPriv.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+	encKey, icb, macKey := deriveKeys(z, r)
+
+	if !hmac.Equal(macTag(macKey, ct), tag) {
+		return nil, ErrMACFailure
+	}
+	return ctr(encKey, icb, ct)
+}
+
+// generateKey generates an ephemeral key pair for the protection scheme.
+func generateKey(scheme uint8) (*ecdh.PrivateKey, error) {
+	c, err := curve(scheme)
+	if err != nil {
+		return nil, err
+	}
+	return c.GenerateKey(rand.Reader)
+}
+
+// deriveKeys derives the encryption key, ICB and MAC key with ANSI-X9.63-KDF using
+// SHA-256, with the ephemeral public key as SharedInfo1.
+func deriveKeys(z, sharedInfo []byte) (encKey, icb, macKey []byte) {
+	l := encKeyLen + icbLen + macKeyLen
+	k := make([]byte, 0, l+sha256.Size)
+	for counter := uint32(1); len(k) < l; counter++ {
+		h := sha256.New()
+		h.Write(z)
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		h.Write(sharedInfo)
+		k = h.Sum(k)
+	}
+	return k[:encKeyLen], k[encKeyLen : encKeyLen+icbLen], k[encKeyLen+icbLen : l]
+}
+
+func ctr(key, icb, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, icb).XORKeyStream(out, in)
+	return out, nil
+}
+
+func macTag(key, ct []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(ct)
+	return mac.Sum(nil)[:macTagLen]
+}
+
+// marshalPublicKey encodes pub in the form used in the scheme output, which is
+// compressed for Profile B.
+func marshalPublicKey(scheme uint8, pub *ecdh.PublicKey) []byte {
+	b := pub.Bytes()
+	if scheme != ProtectionSchemeProfileB {
+		return b
+	}
+
+	// b is 0x04 || X || Y.
+	out := make([]byte, 33)
+	out[0] = 0x02 | b[64]&1
+	copy(out[1:], b[1:33])
+	return out
+}
+
+func unmarshalPublicKey(scheme uint8, b []byte) (*ecdh.PublicKey, error) {
+	c, err := curve(scheme)
+	if err != nil {
+		return nil, err
+	}
+	if scheme != ProtectionSchemeProfileB {
+		return c.NewPublicKey(b)
+	}
+
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), b)
+	if x == nil {
+		return nil, errors.New("invalid compressed public key")
+	}
+	u := make([]byte, 65)
+	u[0] = 0x04
+	x.FillBytes(u[1:33])
+	y.FillBytes(u[33:])
+	return c.NewPublicKey(u)
+}
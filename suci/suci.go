@@ -0,0 +1,376 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package suci provides the concealment of SUPI into SUCI and the de-concealment of it
+with the ECIES protection schemes defined in Annex C, TS 33.501, and the encodings of
+SUCI used in SBI (TS 29.503), NAS (TS 24.501) and NAI (TS 23.003).
+*/
+package suci
+
+import (
+	"crypto/ecdh"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wmnsk/milenage"
+)
+
+// SUPI type definitions.
+const (
+	SUPITypeIMSI uint8 = 0
+	SUPITypeNAI  uint8 = 1
+)
+
+// Protection scheme identifier definitions.
+const (
+	ProtectionSchemeNull     uint8 = 0
+	ProtectionSchemeProfileA uint8 = 1
+	ProtectionSchemeProfileB uint8 = 2
+)
+
+// identityTypeSUCI is the type of identity of SUCI in 5GS mobile identity.
+const identityTypeSUCI uint8 = 1
+
+// SUCI is the Subscription Concealed Identifier.
+//
+// SchemeOutput is the value as it is in NAS, which is the BCD-encoded MSIN for the
+// null-scheme with SUPI type IMSI, and the ephemeral public key, the ciphertext and
+// the MAC tag for the ECIES protection schemes.
+type SUCI struct {
+	SUPIType uint8
+
+	// MCC and MNC are the home network identifier for SUPI type IMSI.
+	MCC, MNC string
+	// Realm is the home network identifier for SUPI type NAI.
+	Realm string
+
+	RoutingIndicator string
+	ProtectionScheme uint8
+	HNPublicKeyID    uint8
+	SchemeOutput     []byte
+}
+
+// Conceal conceals supi ("imsi-" or "nai-" prefixed) into SUCI with the home network
+// public key, whose curve determines the protection scheme (X25519 for Profile A and
+// P-256 for Profile B). If hnPub is nil, the null-scheme is used.
+//
+// mncLen is the number of digits in MNC (2 or 3), which is required for SUPI type IMSI.
+func Conceal(supi string, mncLen int, routingIndicator string, hnPublicKeyID uint8, hnPub *ecdh.PublicKey) (*SUCI, error) {
+	s := &SUCI{RoutingIndicator: routingIndicator, HNPublicKeyID: hnPublicKeyID}
+	if s.RoutingIndicator == "" {
+		s.RoutingIndicator = "0"
+	}
+	if !isDigits(s.RoutingIndicator, 1, 4) {
+		return nil, fmt.Errorf("invalid routing indicator: %s", routingIndicator)
+	}
+
+	var input []byte
+	switch {
+	case strings.HasPrefix(supi, "imsi-"):
+		imsi := supi[len("imsi-"):]
+		if (mncLen != 2 && mncLen != 3) || !isDigits(imsi, 3+mncLen+1, 15) {
+			return nil, fmt.Errorf("invalid IMSI or MNC length: %s, %d", supi, mncLen)
+		}
+		s.SUPIType = SUPITypeIMSI
+		s.MCC, s.MNC = imsi[:3], imsi[3:3+mncLen]
+		input = encodeBCD(imsi[3+mncLen:])
+	case strings.HasPrefix(supi, "nai-"):
+		user, realm, ok := strings.Cut(supi[len("nai-"):], "@")
+		if !ok || user == "" || realm == "" {
+			return nil, fmt.Errorf("invalid NAI: %s", supi)
+		}
+		s.SUPIType = SUPITypeNAI
+		s.Realm = realm
+		input = []byte(user)
+	default:
+		return nil, fmt.Errorf("unsupported SUPI: %s", supi)
+	}
+
+	if hnPub == nil {
+		s.ProtectionScheme = ProtectionSchemeNull
+		s.HNPublicKeyID = 0
+		s.SchemeOutput = input
+		return s, nil
+	}
+
+	switch hnPub.Curve() {
+	case ecdh.X25519():
+		s.ProtectionScheme = ProtectionSchemeProfileA
+	case ecdh.P256():
+		s.ProtectionScheme = ProtectionSchemeProfileB
+	default:
+		return nil, errors.New("unsupported curve of home network public key")
+	}
+
+	eph, err := generateKey(s.ProtectionScheme)
+	if err != nil {
+		return nil, err
+	}
+	s.SchemeOutput, err = encrypt(s.ProtectionScheme, hnPub, eph, input)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Deconceal de-conceals s with the home network private key identified by
+// HNPublicKeyID, and returns SUPI. hnPriv is not used for the null-scheme.
+//
+// ErrMACFailure is returned if the MAC tag in SchemeOutput is not valid.
+func (s *SUCI) Deconceal(hnPriv *ecdh.PrivateKey) (string, error) {
+	input := s.SchemeOutput
+	if s.ProtectionScheme != ProtectionSchemeNull {
+		c, err := curve(s.ProtectionScheme)
+		if err != nil {
+			return "", err
+		}
+		if hnPriv == nil || hnPriv.Curve() != c {
+			return "", fmt.Errorf("home network private key is not for protection scheme %d", s.ProtectionScheme)
+		}
+
+		input, err = decrypt(s.ProtectionScheme, hnPriv, s.SchemeOutput)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch s.SUPIType {
+	case SUPITypeIMSI:
+		msin, err := decodeBCD(input)
+		if err != nil {
+			return "", fmt.Errorf("invalid MSIN: %w", err)
+		}
+		return "imsi-" + s.MCC + s.MNC + msin, nil
+	case SUPITypeNAI:
+		return "nai-" + string(input) + "@" + s.Realm, nil
+	default:
+		return "", fmt.Errorf("unsupported SUPI type: %d", s.SUPIType)
+	}
+}
+
+// String returns s in the form used in SBI, e.g., "suci-0-001-01-0000-0-0-0000000001".
+func (s *SUCI) String() string {
+	hnID := s.MCC + "-" + s.MNC
+	if s.SUPIType != SUPITypeIMSI {
+		hnID = s.Realm
+	}
+	return fmt.Sprintf(
+		"suci-%d-%s-%s-%d-%d-%s",
+		s.SUPIType, hnID, s.RoutingIndicator, s.ProtectionScheme, s.HNPublicKeyID, s.schemeOutputString(),
+	)
+}
+
+// schemeOutputString returns the scheme output in the form used in SBI, which is
+// the MSIN or the username for the null-scheme, and hex for the others.
+func (s *SUCI) schemeOutputString() string {
+	if s.ProtectionScheme != ProtectionSchemeNull {
+		return hex.EncodeToString(s.SchemeOutput)
+	}
+	if s.SUPIType == SUPITypeIMSI {
+		if msin, err := decodeBCD(s.SchemeOutput); err == nil {
+			return msin
+		}
+	}
+	return string(s.SchemeOutput)
+}
+
+// splitSchemeOutput splits the scheme output of the ECIES protection schemes into
+// the ephemeral public key, the ciphertext and the MAC tag.
+func (s *SUCI) splitSchemeOutput() (r, ct, tag []byte) {
+	out := s.SchemeOutput
+	n := ephemeralKeyLen(s.ProtectionScheme)
+	if len(out) < n+macTagLen {
+		return nil, out, nil
+	}
+	return out[:n], out[n : len(out)-macTagLen], out[len(out)-macTagLen:]
+}
+
+// Parse parses SUCI in the form used in SBI.
+func Parse(suci string) (*SUCI, error) {
+	f := strings.Split(suci, "-")
+	if len(f) < 7 || f[0] != "suci" {
+		return nil, fmt.Errorf("invalid SUCI: %s", suci)
+	}
+
+	s := &SUCI{}
+	switch f[1] {
+	case "0":
+		if len(f) != 8 {
+			return nil, fmt.Errorf("invalid SUCI: %s", suci)
+		}
+		if _, err := milenage.NewPLMN(f[2], f[3]); err != nil {
+			return nil, fmt.Errorf("invalid SUCI: %s: %w", suci, err)
+		}
+		s.SUPIType, s.MCC, s.MNC = SUPITypeIMSI, f[2], f[3]
+	case "1":
+		// the realm may contain "-".
+		s.SUPIType, s.Realm = SUPITypeNAI, strings.Join(f[2:len(f)-4], "-")
+	default:
+		return nil, fmt.Errorf("unsupported SUPI type in SUCI: %s", suci)
+	}
+
+	f = f[len(f)-4:]
+	if !isDigits(f[0], 1, 4) {
+		return nil, fmt.Errorf("invalid routing indicator: %s", f[0])
+	}
+	s.RoutingIndicator = f[0]
+
+	scheme, err := strconv.ParseUint(f[1], 10, 4)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protection scheme: %s", f[1])
+	}
+	s.ProtectionScheme = uint8(scheme)
+	keyID, err := strconv.ParseUint(f[2], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid home network public key ID: %s", f[2])
+	}
+	s.HNPublicKeyID = uint8(keyID)
+
+	switch {
+	case s.ProtectionScheme != ProtectionSchemeNull:
+		s.SchemeOutput, err = hex.DecodeString(f[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid scheme output: %w", err)
+		}
+	case s.SUPIType == SUPITypeIMSI:
+		if !isDigits(f[3], 1, 10) {
+			return nil, fmt.Errorf("invalid MSIN: %s", f[3])
+		}
+		s.SchemeOutput = encodeBCD(f[3])
+	default:
+		s.SchemeOutput = []byte(f[3])
+	}
+	return s, nil
+}
+
+// NAI returns s in the NAI format defined in 28.7.3, TS 23.003, e.g.,
+// "type0.rid678.schid0.userid0999999999@nai.5gc.mnc015.mcc234.3gppnetwork.org".
+func (s *SUCI) NAI() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "type%d.rid%s.schid%d.", s.SUPIType, s.RoutingIndicator, s.ProtectionScheme)
+	if s.ProtectionScheme == ProtectionSchemeNull {
+		b.WriteString("userid" + s.schemeOutputString())
+	} else {
+		r, ct, tag := s.splitSchemeOutput()
+		fmt.Fprintf(&b, "hnkey%d.ecckey%x.cip%x.mac%x", s.HNPublicKeyID, r, ct, tag)
+	}
+
+	b.WriteString("@")
+	if s.SUPIType == SUPITypeIMSI {
+		fmt.Fprintf(&b, "nai.5gc.mnc%03s.mcc%s.3gppnetwork.org", s.MNC, s.MCC)
+	} else {
+		b.WriteString(s.Realm)
+	}
+	return b.String()
+}
+
+// Marshal returns the contents of 5GS mobile identity IE (9.11.3.4, TS 24.501)
+// containing s. Only SUPI type IMSI is supported.
+func (s *SUCI) Marshal() ([]byte, error) {
+	if s.SUPIType != SUPITypeIMSI {
+		return nil, fmt.Errorf("unsupported SUPI type: %d", s.SUPIType)
+	}
+	plmn, err := milenage.NewPLMN(s.MCC, s.MNC)
+	if err != nil {
+		return nil, err
+	}
+	if !isDigits(s.RoutingIndicator, 1, 4) {
+		return nil, fmt.Errorf("invalid routing indicator: %s", s.RoutingIndicator)
+	}
+
+	b := make([]byte, 0, 8+len(s.SchemeOutput))
+	b = append(b, s.SUPIType<<4|identityTypeSUCI)
+	b = append(b, plmn.BCD()...)
+	b = append(b, padBCD(encodeBCD(s.RoutingIndicator), 2)...)
+	b = append(b, s.ProtectionScheme&0x0f, s.HNPublicKeyID)
+	return append(b, s.SchemeOutput...), nil
+}
+
+// ParseMobileIdentity parses the contents of 5GS mobile identity IE containing SUCI.
+func ParseMobileIdentity(b []byte) (*SUCI, error) {
+	if len(b) < 8 {
+		return nil, errors.New("too short 5GS mobile identity")
+	}
+	if b[0]&0x07 != identityTypeSUCI {
+		return nil, fmt.Errorf("unexpected type of identity: %d", b[0]&0x07)
+	}
+	if typ := b[0] >> 4 & 0x07; typ != SUPITypeIMSI {
+		return nil, fmt.Errorf("unsupported SUPI format: %d", typ)
+	}
+
+	plmn, err := milenage.ParsePLMN(b[1:4])
+	if err != nil {
+		return nil, err
+	}
+	rid, err := decodeBCD(b[4:6])
+	if err != nil || rid == "" {
+		return nil, fmt.Errorf("invalid routing indicator: %x", b[4:6])
+	}
+
+	return &SUCI{
+		SUPIType:         SUPITypeIMSI,
+		MCC:              plmn.MCC,
+		MNC:              plmn.MNC,
+		RoutingIndicator: rid,
+		ProtectionScheme: b[6] & 0x0f,
+		HNPublicKeyID:    b[7],
+		SchemeOutput:     append([]byte(nil), b[8:]...),
+	}, nil
+}
+
+// encodeBCD encodes digits in BCD with the filler 0xf for the odd number of digits.
+func encodeBCD(digits string) []byte {
+	b := make([]byte, (len(digits)+1)/2)
+	for i := range b {
+		hi := byte(0x0f)
+		if 2*i+1 < len(digits) {
+			hi = digits[2*i+1] - '0'
+		}
+		b[i] = hi<<4 | (digits[2*i] - '0')
+	}
+	return b
+}
+
+// padBCD pads b with the filler 0xff to n octets.
+func padBCD(b []byte, n int) []byte {
+	for len(b) < n {
+		b = append(b, 0xff)
+	}
+	return b
+}
+
+// decodeBCD decodes BCD digits until the filler 0xf, after which only the fillers are allowed.
+func decodeBCD(b []byte) (string, error) {
+	digits := make([]byte, 0, len(b)*2)
+	filled := false
+	for _, o := range b {
+		for _, d := range []byte{o & 0x0f, o >> 4} {
+			switch {
+			case d == 0x0f:
+				filled = true
+			case filled || d > 9:
+				return "", fmt.Errorf("invalid BCD digits: %x", b)
+			default:
+				digits = append(digits, '0'+d)
+			}
+		}
+	}
+	return string(digits), nil
+}
+
+func isDigits(s string, minLen, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,223 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package suci_test
+
+import (
+	"crypto/ecdh"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage/suci"
+)
+
+// test vectors in C.4.3 and C.4.4, TS 33.501.
+func newKeys(t *testing.T) (profileA, profileB *ecdh.PrivateKey) {
+	t.Helper()
+
+	profileA, err := ecdh.X25519().NewPrivateKey([]byte{
+		0xc5, 0x3c, 0x22, 0x20, 0x8b, 0x61, 0x86, 0x0b, 0x06, 0xc6, 0x2e, 0x54, 0x06, 0xa7, 0xb3, 0x30,
+		0xc2, 0xb5, 0x77, 0xaa, 0x55, 0x58, 0x98, 0x15, 0x10, 0xd1, 0x28, 0x24, 0x7d, 0x38, 0xbd, 0x1d,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profileB, err = ecdh.P256().NewPrivateKey([]byte{
+		0xf1, 0xab, 0x10, 0x74, 0x47, 0x7e, 0xbc, 0xc7, 0xf5, 0x54, 0xea, 0x1c, 0x5f, 0xc3, 0x68, 0xb1,
+		0x61, 0x67, 0x30, 0x15, 0x5e, 0x00, 0x41, 0xac, 0x44, 0x7d, 0x63, 0x01, 0x97, 0x5f, 0xec, 0xda,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profileA, profileB
+}
+
+func TestDeconceal(t *testing.T) {
+	profileA, profileB := newKeys(t)
+
+	for _, c := range []struct {
+		description string
+		scheme      uint8
+		key         *ecdh.PrivateKey
+		output      []byte
+	}{
+		{
+			"Profile A", suci.ProtectionSchemeProfileA, profileA,
+			[]byte{
+				0xb2, 0xe9, 0x2f, 0x83, 0x60, 0x55, 0xa2, 0x55, 0x83, 0x7d, 0xeb, 0xf8, 0x50, 0xb5, 0x28, 0x99,
+				0x7c, 0xe0, 0x20, 0x1c, 0xb8, 0x2a, 0xdf, 0xe4, 0xbe, 0x1f, 0x58, 0x7d, 0x07, 0xd8, 0x45, 0x7d,
+				0xcb, 0x02, 0x35, 0x24, 0x10, 0xcd, 0xdd, 0x9e, 0x73, 0x0e, 0xf3, 0xfa, 0x87,
+			},
+		}, {
+			"Profile B", suci.ProtectionSchemeProfileB, profileB,
+			[]byte{
+				0x03, 0x9a, 0xab, 0x83, 0x76, 0x59, 0x70, 0x21, 0xe8, 0x55, 0x67, 0x9a, 0x97, 0x78, 0xea, 0x0b,
+				0x67, 0x39, 0x6e, 0x68, 0xc6, 0x6d, 0xf3, 0x2c, 0x0f, 0x41, 0xe9, 0xac, 0xca, 0x2d, 0xa9, 0xb9,
+				0xd1, 0x46, 0xa3, 0x3f, 0xc2, 0x71, 0x6a, 0xc7, 0xda, 0xe9, 0x6a, 0xa3, 0x0a, 0x4d,
+			},
+		},
+	} {
+		s := &suci.SUCI{
+			SUPIType:         suci.SUPITypeIMSI,
+			MCC:              "001",
+			MNC:              "01",
+			RoutingIndicator: "0",
+			ProtectionScheme: c.scheme,
+			SchemeOutput:     c.output,
+		}
+		got, err := s.Deconceal(c.key)
+		if err != nil {
+			t.Fatalf("%s: %v", c.description, err)
+		}
+		if diff := cmp.Diff(got, "imsi-00101001002086"); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+
+		s.SchemeOutput[len(s.SchemeOutput)-1] ^= 1
+		if _, err := s.Deconceal(c.key); !errors.Is(err, suci.ErrMACFailure) {
+			t.Errorf("%s: tampered SUCI is expected to fail: %v", c.description, err)
+		}
+	}
+}
+
+func TestConceal(t *testing.T) {
+	profileA, profileB := newKeys(t)
+
+	for _, c := range []struct {
+		description string
+		supi        string
+		key         *ecdh.PrivateKey
+		scheme      uint8
+	}{
+		{"null-scheme", "imsi-001010123456789", nil, suci.ProtectionSchemeNull},
+		{"Profile A", "imsi-001010123456789", profileA, suci.ProtectionSchemeProfileA},
+		{"Profile B", "imsi-001010123456789", profileB, suci.ProtectionSchemeProfileB},
+		{"NAI", "nai-user@example.com", profileA, suci.ProtectionSchemeProfileA},
+	} {
+		var pub *ecdh.PublicKey
+		if c.key != nil {
+			pub = c.key.PublicKey()
+		}
+		s, err := suci.Conceal(c.supi, 2, "1234", 1, pub)
+		if err != nil {
+			t.Fatalf("%s: %v", c.description, err)
+		}
+		if s.ProtectionScheme != c.scheme {
+			t.Errorf("%s: unexpected protection scheme: %d", c.description, s.ProtectionScheme)
+		}
+
+		// round trip in the form used in SBI.
+		parsed, err := suci.Parse(s.String())
+		if err != nil {
+			t.Fatalf("%s: %v", c.description, err)
+		}
+		if diff := cmp.Diff(parsed, s); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+
+		got, err := parsed.Deconceal(c.key)
+		if err != nil {
+			t.Fatalf("%s: %v", c.description, err)
+		}
+		if diff := cmp.Diff(got, c.supi); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	s, err := suci.Parse("suci-0-001-01-0000-0-0-0000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &suci.SUCI{
+		SUPIType:         suci.SUPITypeIMSI,
+		MCC:              "001",
+		MNC:              "01",
+		RoutingIndicator: "0000",
+		SchemeOutput:     []byte{0x00, 0x00, 0x00, 0x00, 0x10},
+	}
+	if diff := cmp.Diff(s, want); diff != "" {
+		t.Errorf("Parse failed: \n%s", diff)
+	}
+	if diff := cmp.Diff(s.String(), "suci-0-001-01-0000-0-0-0000000001"); diff != "" {
+		t.Errorf("String failed: \n%s", diff)
+	}
+
+	s, err = suci.Parse("suci-1-my-realm.example.com-0-1-2-00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff0011223344556677")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.SUPIType != suci.SUPITypeNAI || s.Realm != "my-realm.example.com" || s.HNPublicKeyID != 2 {
+		t.Errorf("unexpected SUCI: %+v", s)
+	}
+
+	for _, c := range []string{
+		"imsi-001010000000001",
+		"suci-0-001-1-0000-0-0-0000000001",
+		"suci-0-001-01-00000-0-0-0000000001",
+		"suci-0-001-01-0000-1-0-xyz",
+		"suci-2-001-01-0000-0-0-0000000001",
+	} {
+		if _, err := suci.Parse(c); err == nil {
+			t.Errorf("%s: expected error", c)
+		}
+	}
+}
+
+func TestNAI(t *testing.T) {
+	// examples in 28.7.3, TS 23.003.
+	s, err := suci.Parse("suci-0-234-15-678-0-0-0999999999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(s.NAI(), "type0.rid678.schid0.userid0999999999@nai.5gc.mnc015.mcc234.3gppnetwork.org"); diff != "" {
+		t.Errorf("NAI failed: \n%s", diff)
+	}
+
+	s.ProtectionScheme, s.HNPublicKeyID = suci.ProtectionSchemeProfileA, 27
+	s.SchemeOutput = []byte{
+		0xb2, 0xe9, 0x2f, 0x83, 0x60, 0x55, 0xa2, 0x55, 0x83, 0x7d, 0xeb, 0xf8, 0x50, 0xb5, 0x28, 0x99,
+		0x7c, 0xe0, 0x20, 0x1c, 0xb8, 0x2a, 0xdf, 0xe4, 0xbe, 0x1f, 0x58, 0x7d, 0x07, 0xd8, 0x45, 0x7d,
+		0xcb, 0x02, 0x35, 0x24, 0x10, 0xcd, 0xdd, 0x9e, 0x73, 0x0e, 0xf3, 0xfa, 0x87,
+	}
+	want := "type0.rid678.schid1.hnkey27.ecckeyb2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457d" +
+		".cipcb02352410.maccddd9e730ef3fa87@nai.5gc.mnc015.mcc234.3gppnetwork.org"
+	if diff := cmp.Diff(s.NAI(), want); diff != "" {
+		t.Errorf("NAI failed: \n%s", diff)
+	}
+}
+
+func TestMobileIdentity(t *testing.T) {
+	for _, c := range []struct {
+		description string
+		suci        string
+		serialized  string
+	}{
+		{"2-digit MNC", "suci-0-001-01-0000-0-0-0000000001", "01" + "00f110" + "0000" + "00" + "00" + "0000000010"},
+		{"3-digit MNC", "suci-0-310-410-12-1-3-00112233", "01" + "130014" + "21ff" + "01" + "03" + "00112233"},
+	} {
+		s, err := suci.Parse(c.suci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := s.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(hex.EncodeToString(b), c.serialized); diff != "" {
+			t.Errorf("%s: Marshal failed: \n%s", c.description, diff)
+		}
+
+		parsed, err := suci.ParseMobileIdentity(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(parsed, s); diff != "" {
+			t.Errorf("%s: ParseMobileIdentity failed: \n%s", c.description, diff)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package swx provides the encoding and decoding of SIP-Auth-Data-Item AVP used in
+MAR/MAA over SWx (TS 29.273), to serialise the EAP-AKA' vectors computed by the milenage
+package for a local 3GPP AAA/HSS stand-in.
+
+The AVPs are built on the AVP type in the diameter package.
+*/
+package swx
+
+import (
+	"fmt"
+
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/diameter"
+)
+
+// AVP code definitions (TS 29.273 and TS 29.229).
+const (
+	CodeSIPNumberAuthItems      uint32 = 607
+	CodeSIPAuthenticationScheme uint32 = 608
+	CodeSIPAuthenticate         uint32 = 609
+	CodeSIPAuthorization        uint32 = 610
+	CodeSIPAuthDataItem         uint32 = 612
+	CodeSIPItemNumber           uint32 = 613
+	CodeConfidentialityKey      uint32 = 625
+	CodeIntegrityKey            uint32 = 626
+	CodeANID                    uint32 = 1504
+)
+
+// SIP-Authentication-Scheme values used in SWx.
+const (
+	AuthenticationSchemeEAPAKA      = "EAP-AKA"
+	AuthenticationSchemeEAPAKAPrime = "EAP-AKA'"
+)
+
+// SIPAuthDataItem is the SIP-Auth-Data-Item AVP (8.2.3.9, TS 29.273).
+//
+// In MAA, Authenticate is RAND || AUTN and Authorization is XRES, and CK and IK are CK' and IK'
+// for EAP-AKA'. In MAR for re-synchronisation, Authorization is RAND || AUTS.
+type SIPAuthDataItem struct {
+	// ItemNumber is the SIP-Item-Number AVP, which is omitted if zero.
+	ItemNumber           uint32
+	AuthenticationScheme string
+	Authenticate         []byte
+	Authorization        []byte
+	CK                   []byte
+	IK                   []byte
+}
+
+// NewSIPAuthDataItem creates a new SIPAuthDataItem for EAP-AKA' from the values in m, with
+// CK' and IK' derived for the access network identity anid (e.g., "WLAN").
+//
+// Note that this function should be called after F1 and F2345 are done.
+func NewSIPAuthDataItem(m *milenage.Milenage, anid string) (*SIPAuthDataItem, error) {
+	autn, err := m.GenerateAUTN()
+	if err != nil {
+		return nil, err
+	}
+	ckPrime, ikPrime, err := m.ComputeCKIKPrime(anid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SIPAuthDataItem{
+		AuthenticationScheme: AuthenticationSchemeEAPAKAPrime,
+		Authenticate:         append(append([]byte{}, m.RAND...), autn...),
+		Authorization:        append([]byte{}, m.RES...),
+		CK:                   ckPrime,
+		IK:                   ikPrime,
+	}, nil
+}
+
+// NewResynchronizationItem creates a new SIPAuthDataItem sent in MAR to request
+// re-synchronisation, with RAND and AUTS in SIP-Authorization.
+func NewResynchronizationItem(scheme string, rand, auts []byte) (*SIPAuthDataItem, error) {
+	if len(rand) != 16 {
+		return nil, fmt.Errorf("length of RAND should be %d, got: %d", 16, len(rand))
+	}
+	if len(auts) != 14 {
+		return nil, fmt.Errorf("length of AUTS should be %d, got: %d", 14, len(auts))
+	}
+	return &SIPAuthDataItem{
+		AuthenticationScheme: scheme,
+		Authorization:        append(append([]byte{}, rand...), auts...),
+	}, nil
+}
+
+// RANDAndAUTN splits SIP-Authenticate into RAND and AUTN.
+func (i *SIPAuthDataItem) RANDAndAUTN() (rand, autn []byte, err error) {
+	if l := len(i.Authenticate); l != 32 {
+		return nil, nil, fmt.Errorf("length of SIP-Authenticate should be %d, got: %d", 32, l)
+	}
+	return i.Authenticate[:16], i.Authenticate[16:], nil
+}
+
+// RANDAndAUTS splits SIP-Authorization in MAR into RAND and AUTS.
+// RAND and AUTS can be used with milenage.Milenage.VerifyAUTS to re-synchronise SQN.
+func (i *SIPAuthDataItem) RANDAndAUTS() (rand, auts []byte, err error) {
+	if l := len(i.Authorization); l != 30 {
+		return nil, nil, fmt.Errorf("length of SIP-Authorization should be %d, got: %d", 30, l)
+	}
+	return i.Authorization[:16], i.Authorization[16:], nil
+}
+
+// AVP returns i as an AVP. The AVPs with empty value are omitted.
+func (i *SIPAuthDataItem) AVP() *diameter.AVP {
+	var avps []*diameter.AVP
+	if i.ItemNumber != 0 {
+		avps = append(avps, diameter.NewUnsigned32AVP(CodeSIPItemNumber, i.ItemNumber))
+	}
+	for _, a := range []struct {
+		code  uint32
+		value []byte
+	}{
+		{CodeSIPAuthenticationScheme, []byte(i.AuthenticationScheme)},
+		{CodeSIPAuthenticate, i.Authenticate},
+		{CodeSIPAuthorization, i.Authorization},
+		{CodeConfidentialityKey, i.CK},
+		{CodeIntegrityKey, i.IK},
+	} {
+		if len(a.value) != 0 {
+			avps = append(avps, diameter.NewAVP(a.code, a.value))
+		}
+	}
+	return diameter.NewGroupedAVP(CodeSIPAuthDataItem, avps...)
+}
+
+// Marshal returns the byte sequence of i as an AVP.
+func (i *SIPAuthDataItem) Marshal() []byte {
+	return i.AVP().Marshal()
+}
+
+// ParseSIPAuthDataItem decodes the SIP-Auth-Data-Item AVP in b.
+// Unknown AVPs inside are ignored.
+func ParseSIPAuthDataItem(b []byte) (*SIPAuthDataItem, error) {
+	a, _, err := diameter.ParseAVP(b)
+	if err != nil {
+		return nil, err
+	}
+	if a.Code != CodeSIPAuthDataItem {
+		return nil, fmt.Errorf("unexpected AVP code: %d", a.Code)
+	}
+	avps, err := diameter.ParseAVPs(a.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &SIPAuthDataItem{}
+	for _, avp := range avps {
+		v := append([]byte{}, avp.Data...)
+		switch avp.Code {
+		case CodeSIPItemNumber:
+			if i.ItemNumber, err = avp.Unsigned32(); err != nil {
+				return nil, err
+			}
+		case CodeSIPAuthenticationScheme:
+			i.AuthenticationScheme = string(v)
+		case CodeSIPAuthenticate:
+			i.Authenticate = v
+		case CodeSIPAuthorization:
+			i.Authorization = v
+		case CodeConfidentialityKey:
+			i.CK = v
+		case CodeIntegrityKey:
+			i.IK = v
+		}
+	}
+	return i, nil
+}
+
+// NewSIPNumberAuthItems creates a new SIP-Number-Auth-Items AVP, which is the number of
+// SIP-Auth-Data-Item requested in MAR or included in MAA.
+func NewSIPNumberAuthItems(n uint32) *diameter.AVP {
+	return diameter.NewUnsigned32AVP(CodeSIPNumberAuthItems, n)
+}
+
+// NewANID creates a new ANID AVP (5.2.3.7, TS 29.273), which is the access network
+// identity used to derive CK' and IK'.
+func NewANID(anid string) *diameter.AVP {
+	return diameter.NewAVP(CodeANID, []byte(anid))
+}
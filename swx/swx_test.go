@@ -0,0 +1,138 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package swx_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/swx"
+)
+
+func TestSIPAuthDataItem(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := swx.NewSIPAuthDataItem(m, "WLAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.ItemNumber = 1
+
+	ckPrime, ikPrime, err := m.ComputeCKIKPrime("WLAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(item, &swx.SIPAuthDataItem{
+		ItemNumber:           1,
+		AuthenticationScheme: swx.AuthenticationSchemeEAPAKAPrime,
+		Authenticate: []byte{
+			0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35,
+			0x55, 0xf3, 0x28, 0xb4, 0x35, 0x77, 0xb9, 0xb9, 0x4a, 0x9f, 0xfa, 0xc3, 0x54, 0xdf, 0xaf, 0xb3,
+		},
+		Authorization: m.RES,
+		CK:            ckPrime,
+		IK:            ikPrime,
+	}); diff != "" {
+		t.Error(diff)
+	}
+
+	b := item.Marshal()
+	// 12 + SIP-Item-Number 16 + SIP-Authentication-Scheme 20 + SIP-Authenticate 44
+	// + SIP-Authorization 20 + Confidentiality-Key 28 + Integrity-Key 28.
+	if diff := cmp.Diff(b[:12], []byte{0x00, 0x00, 0x02, 0x64, 0xc0, 0x00, 0x00, 0xa8, 0x00, 0x00, 0x28, 0xaf}); diff != "" {
+		t.Error(diff)
+	}
+	if l := len(b); l != 168 {
+		t.Errorf("unexpected length: %d", l)
+	}
+
+	got, err := swx.ParseSIPAuthDataItem(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, item); diff != "" {
+		t.Error(diff)
+	}
+
+	rand, autn, err := got.RANDAndAUTN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(rand, m.RAND); diff != "" {
+		t.Error(diff)
+	}
+	if ok, err := m.VerifyMACA(autn[8:]); err != nil || !ok {
+		t.Errorf("MAC-A verification failed: %v", err)
+	}
+
+	if _, err := swx.NewSIPAuthDataItem(m, ""); err == nil {
+		t.Error("expected error with empty ANID")
+	}
+}
+
+func TestResynchronizationItem(t *testing.T) {
+	m := milenage.NewWithOPc(
+		[]byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc},
+		[]byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf},
+		[]byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35},
+		0xff9bb4d0b607,
+		0xb9b9,
+	)
+	if err := m.ComputeAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// UE has SQNMS=0x1000 and rejects the AUTN received.
+	ue := milenage.NewWithOPc(m.K, m.OPc, m.RAND, 0x1000, 0)
+	auts, err := ue.GenerateAUTS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := swx.NewResynchronizationItem(swx.AuthenticationSchemeEAPAKAPrime, m.RAND, auts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := swx.ParseSIPAuthDataItem(item.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, item); diff != "" {
+		t.Error(diff)
+	}
+
+	rand, gotAUTS, err := got.RANDAndAUTS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hss := milenage.NewWithOPc(m.K, m.OPc, rand, 0, 0)
+	sqnMS, ok, err := hss.VerifyAUTS(gotAUTS)
+	if err != nil || !ok {
+		t.Fatalf("AUTS verification failed: %v, %v", ok, err)
+	}
+	if sqn := milenage.SQNToUint64([6]byte(sqnMS)); sqn != 0x1000 {
+		t.Errorf("unexpected SQNMS: %x", sqn)
+	}
+
+	if _, _, err := got.RANDAndAUTN(); err == nil {
+		t.Error("expected error without SIP-Authenticate")
+	}
+}
+
+func TestANID(t *testing.T) {
+	if diff := cmp.Diff(swx.NewANID("WLAN").Marshal(), []byte{0x00, 0x00, 0x05, 0xe0, 0xc0, 0x00, 0x00, 0x10, 0x00, 0x00, 0x28, 0xaf, 0x57, 0x4c, 0x41, 0x4e}); diff != "" {
+		t.Error(diff)
+	}
+}
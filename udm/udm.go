@@ -0,0 +1,356 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+/*
+Package udm provides a minimal stand-in of UDM, which implements the generate-auth-data
+operation of Nudm_UEAuthentication service defined in TS 29.503 over HTTP.
+
+This is meant to be used in the integration tests of AMF/AUSF, e.g., with net/http/httptest,
+and is not a complete implementation of UDM.
+*/
+package udm
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/auc"
+	"github.com/wmnsk/milenage/suci"
+)
+
+// Path is the path pattern of the generate-auth-data operation.
+const Path = "/nudm-ueau/v1/{supiOrSuci}/security-information/generate-auth-data"
+
+// AuthType definitions.
+const (
+	AuthType5GAKA       = "5G_AKA"
+	AuthTypeEAPAKAPrime = "EAP_AKA_PRIME"
+)
+
+// AvType definitions.
+const (
+	AvType5GHEAKA     = "5G_HE_AKA"
+	AvTypeEAPAKAPrime = "EAP_AKA_PRIME"
+)
+
+// AuthenticationInfoRequest is the request body of generate-auth-data.
+type AuthenticationInfoRequest struct {
+	ServingNetworkName    string                 `json:"servingNetworkName"`
+	ResynchronizationInfo *ResynchronizationInfo `json:"resynchronizationInfo,omitempty"`
+	AusfInstanceID        string                 `json:"ausfInstanceId,omitempty"`
+}
+
+// ResynchronizationInfo is the RAND and AUTS sent from UE on synchronisation failure.
+type ResynchronizationInfo struct {
+	RAND string `json:"rand"`
+	AUTS string `json:"auts"`
+}
+
+// AuthenticationInfoResult is the response body of generate-auth-data.
+type AuthenticationInfoResult struct {
+	AuthType             string                `json:"authType"`
+	AuthenticationVector *AuthenticationVector `json:"authenticationVector,omitempty"`
+	SUPI                 string                `json:"supi,omitempty"`
+}
+
+// AuthenticationVector is either of the 5G HE AV (Av5GHeAka) or the transformed
+// authentication vector for EAP-AKA' (AvEapAkaPrime), distinguished by AvType.
+type AuthenticationVector struct {
+	AvType string `json:"avType"`
+	RAND   string `json:"rand"`
+	AUTN   string `json:"autn"`
+
+	// for 5G_HE_AKA.
+	XRESStar string `json:"xresStar,omitempty"`
+	KAUSF    string `json:"kausf,omitempty"`
+
+	// for EAP_AKA_PRIME.
+	XRES    string `json:"xres,omitempty"`
+	CKPrime string `json:"ckPrime,omitempty"`
+	IKPrime string `json:"ikPrime,omitempty"`
+}
+
+// ProblemDetails is the body of error responses.
+//
+// ProblemDetails implements error, which is returned by GenerateAuthData.
+type ProblemDetails struct {
+	Title  string `json:"title,omitempty"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Cause  string `json:"cause,omitempty"`
+}
+
+// Error implements error.
+func (p *ProblemDetails) Error() string {
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Cause, p.Detail)
+}
+
+func newProblem(status int, cause string, format string, a ...any) *ProblemDetails {
+	return &ProblemDetails{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: fmt.Sprintf(format, a...),
+		Cause:  cause,
+	}
+}
+
+// Handler is the http.Handler that serves generate-auth-data with the credentials in the store.
+type Handler struct {
+	// AuthType is the authentication method selected for all the subscribers,
+	// which is either AuthType5GAKA (default) or AuthTypeEAPAKAPrime.
+	AuthType string
+
+	// HomeNetworkKeys are the home network private keys used to de-conceal SUCI,
+	// indexed by the home network public key identifier. SUCI with the null-scheme
+	// is accepted without any keys.
+	HomeNetworkKeys map[uint8]*ecdh.PrivateKey
+
+	store auc.CredentialStore
+	mux   *http.ServeMux
+}
+
+// NewHandler creates a new Handler that serves the operation at Path with store.
+func NewHandler(store auc.CredentialStore) *Handler {
+	h := &Handler{AuthType: AuthType5GAKA, store: store, mux: http.NewServeMux()}
+	h.mux.HandleFunc("POST "+Path, h.generateAuthData)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) generateAuthData(w http.ResponseWriter, r *http.Request) {
+	req := &AuthenticationInfoRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		WriteProblem(w, newProblem(http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "invalid request body: %v", err))
+		return
+	}
+
+	res, err := h.GenerateAuthData(r.Context(), r.PathValue("supiOrSuci"), req)
+	if err != nil {
+		WriteProblem(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, "application/json", res)
+}
+
+// WriteProblem writes err as ProblemDetails to w. If err is not *ProblemDetails,
+// it is sent as 500 Internal Server Error.
+func WriteProblem(w http.ResponseWriter, err error) {
+	var p *ProblemDetails
+	if !errors.As(err, &p) {
+		p = newProblem(http.StatusInternalServerError, "SYSTEM_FAILURE", "%v", err)
+	}
+	writeJSON(w, p.Status, "application/problem+json", p)
+}
+
+// GenerateAuthData generates an authentication vector for the subscriber, which is
+// the operation served over HTTP by h. This can be used to call the operation in-process,
+// e.g., from a stand-in AUSF. The errors that should be sent to the peer are returned
+// as *ProblemDetails.
+func (h *Handler) GenerateAuthData(ctx context.Context, supiOrSuci string, req *AuthenticationInfoRequest) (*AuthenticationInfoResult, error) {
+	supi, err := h.resolveSUPI(supiOrSuci)
+	if err != nil {
+		return nil, err
+	}
+
+	// Any forms of SNN, e.g., the one of SNPN with NID and ServingNetworkNameNSWO, are
+	// accepted, and it is used as it is in the KDFs.
+	if err := milenage.ValidateServingNetworkName(req.ServingNetworkName); err != nil {
+		return nil, newProblem(http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "%v", err)
+	}
+
+	cred, err := h.store.Lookup(ctx, supi)
+	if err != nil {
+		if errors.Is(err, auc.ErrNotFound) {
+			return nil, newProblem(http.StatusNotFound, "USER_NOT_FOUND", "%s: %v", supi, err)
+		}
+		return nil, err
+	}
+
+	if req.ResynchronizationInfo != nil {
+		if err := h.resynchronize(ctx, supi, cred, req.ResynchronizationInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	sqn, err := h.store.IncrementSQN(ctx, supi)
+	if err != nil {
+		return nil, err
+	}
+	cred.SQN = sqn
+
+	authType := h.AuthType
+	if authType == "" {
+		authType = AuthType5GAKA
+	}
+
+	var av *AuthenticationVector
+	switch authType {
+	case AuthType5GAKA:
+		av, err = generateVector(cred, req.ServingNetworkName)
+	case AuthTypeEAPAKAPrime:
+		av, err = generateEAPAKAPrimeVector(cred, req.ServingNetworkName)
+	default:
+		err = fmt.Errorf("unsupported AuthType: %s", authType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthenticationInfoResult{
+		AuthType:             authType,
+		AuthenticationVector: av,
+		SUPI:                 supi,
+	}, nil
+}
+
+// resolveSUPI returns SUPI identified by supiOrSuci, de-concealing it if it is SUCI.
+func (h *Handler) resolveSUPI(supiOrSuci string) (string, error) {
+	if strings.HasPrefix(supiOrSuci, "imsi-") {
+		return supiOrSuci, nil
+	}
+	if !strings.HasPrefix(supiOrSuci, "suci-") {
+		return "", newProblem(http.StatusNotImplemented, "UNSUPPORTED_UE_IDENTITY", "unsupported UE identity: %s", supiOrSuci)
+	}
+
+	s, err := suci.Parse(supiOrSuci)
+	if err != nil {
+		return "", newProblem(http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "%v", err)
+	}
+	if s.SUPIType != suci.SUPITypeIMSI {
+		return "", newProblem(http.StatusNotImplemented, "UNSUPPORTED_UE_IDENTITY", "unsupported UE identity: %s", supiOrSuci)
+	}
+
+	var key *ecdh.PrivateKey
+	if s.ProtectionScheme != suci.ProtectionSchemeNull {
+		key = h.HomeNetworkKeys[s.HNPublicKeyID]
+		if key == nil {
+			return "", newProblem(
+				http.StatusNotImplemented, "UNSUPPORTED_PROTECTION_SCHEME",
+				"no home network key for protection scheme %d and key ID %d", s.ProtectionScheme, s.HNPublicKeyID,
+			)
+		}
+	}
+
+	supi, err := s.Deconceal(key)
+	if err != nil {
+		return "", newProblem(http.StatusForbidden, "AUTHENTICATION_REJECTED", "failed to de-conceal SUCI: %v", err)
+	}
+	return supi, nil
+}
+
+// resynchronize verifies AUTS and sets SQN in the store to SQNMS retrieved from it.
+func (h *Handler) resynchronize(ctx context.Context, supi string, cred *auc.Credential, info *ResynchronizationInfo) error {
+	rnd, err := hex.DecodeString(info.RAND)
+	if err != nil || len(rnd) != 16 {
+		return newProblem(http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "invalid RAND: %s", info.RAND)
+	}
+	auts, err := hex.DecodeString(info.AUTS)
+	if err != nil || len(auts) != 14 {
+		return newProblem(http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "invalid AUTS: %s", info.AUTS)
+	}
+
+	m, err := cred.Milenage(rnd)
+	if err != nil {
+		return err
+	}
+	sqnMS, ok, err := m.VerifyAUTS(auts)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newProblem(http.StatusForbidden, "AUTHENTICATION_REJECTED", "MAC-S verification failed")
+	}
+
+	var b [6]byte
+	copy(b[:], sqnMS)
+	return h.store.SetSQN(ctx, supi, milenage.SQNToUint64(b))
+}
+
+// computeVector computes f1-f5 and AUTN with a new RAND.
+func computeVector(cred *auc.Credential) (m *milenage.Milenage, autn []byte, err error) {
+	rnd := make([]byte, 16)
+	if _, err := rand.Read(rnd); err != nil {
+		return nil, nil, err
+	}
+
+	m, err = cred.Milenage(rnd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := m.F1(); err != nil {
+		return nil, nil, err
+	}
+	if _, _, _, _, err := m.F2345(); err != nil {
+		return nil, nil, err
+	}
+	autn, err = m.GenerateAUTN()
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, autn, nil
+}
+
+// generateVector generates a 5G HE AV with a new RAND, as described in 6.1.3.2, TS 33.501.
+func generateVector(cred *auc.Credential, snn string) (*AuthenticationVector, error) {
+	m, autn, err := computeVector(cred)
+	if err != nil {
+		return nil, err
+	}
+	xresStar, err := m.ComputeRESStarWithSNN(snn)
+	if err != nil {
+		return nil, err
+	}
+	kausf, err := m.ComputeKAUSFWithSNN(snn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthenticationVector{
+		AvType:   AvType5GHEAKA,
+		RAND:     hex.EncodeToString(m.RAND),
+		AUTN:     hex.EncodeToString(autn),
+		XRESStar: hex.EncodeToString(xresStar),
+		KAUSF:    hex.EncodeToString(kausf),
+	}, nil
+}
+
+// generateEAPAKAPrimeVector generates a transformed authentication vector for EAP-AKA'
+// with a new RAND, as described in 6.1.3.1, TS 33.501.
+func generateEAPAKAPrimeVector(cred *auc.Credential, snn string) (*AuthenticationVector, error) {
+	m, autn, err := computeVector(cred)
+	if err != nil {
+		return nil, err
+	}
+	ckPrime, ikPrime, err := m.ComputeCKIKPrime(snn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthenticationVector{
+		AvType:  AvTypeEAPAKAPrime,
+		RAND:    hex.EncodeToString(m.RAND),
+		AUTN:    hex.EncodeToString(autn),
+		XRES:    hex.EncodeToString(m.RES),
+		CKPrime: hex.EncodeToString(ckPrime),
+		IKPrime: hex.EncodeToString(ikPrime),
+	}, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, contentType string, v any) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,282 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package udm_test
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wmnsk/milenage"
+	"github.com/wmnsk/milenage/auc"
+	"github.com/wmnsk/milenage/suci"
+	"github.com/wmnsk/milenage/udm"
+)
+
+const (
+	supi = "imsi-001010000000001"
+	snn  = "5G:mnc001.mcc001.3gppnetwork.org"
+)
+
+var (
+	k   = []byte{0x46, 0x5b, 0x5c, 0xe8, 0xb1, 0x99, 0xb4, 0x9f, 0xaa, 0x5f, 0x0a, 0x2e, 0xe2, 0x38, 0xa6, 0xbc}
+	opc = []byte{0xcd, 0x63, 0xcb, 0x71, 0x95, 0x4a, 0x9f, 0x4e, 0x48, 0xa5, 0x99, 0x4e, 0x37, 0xa0, 0x2b, 0xaf}
+)
+
+func newServer(t *testing.T, sqn uint64) *httptest.Server {
+	t.Helper()
+
+	store := auc.NewMemoryStore(&auc.Credential{SUPI: supi, K: k, OPc: opc, AMF: 0x8000, SQN: sqn})
+	srv := httptest.NewServer(udm.NewHandler(store))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func post(t *testing.T, srv *httptest.Server, id string, req *udm.AuthenticationInfoRequest, v any) int {
+	t.Helper()
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := srv.URL + strings.Replace(udm.Path, "{supiOrSuci}", id, 1)
+	rsp, err := http.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	if err := json.NewDecoder(rsp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return rsp.StatusCode
+}
+
+// verifyVector verifies the vector in the way UE does, and returns SQN in AUTN.
+func verifyVector(t *testing.T, av *udm.AuthenticationVector) uint64 {
+	t.Helper()
+
+	rnd, err := hex.DecodeString(av.RAND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	autn, err := hex.DecodeString(av.AUTN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := milenage.NewWithOPc(k, opc, rnd, 0, 0x8000)
+	if _, _, _, _, err := m.F2345(); err != nil {
+		t.Fatal(err)
+	}
+	var sqn [6]byte
+	for i := range sqn {
+		sqn[i] = autn[i] ^ m.AK[i]
+	}
+	m.SQN = sqn[:]
+
+	if ok, err := m.VerifyMACA(autn[8:]); err != nil || !ok {
+		t.Errorf("MAC-A verification failed: %v", err)
+	}
+	xresStar, err := hex.DecodeString(av.XRESStar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := m.VerifyRESStar(xresStar, "001", "01"); err != nil || !ok {
+		t.Errorf("RES* verification failed: %v", err)
+	}
+	kausf, err := m.ComputeKAUSF("001", "01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(kausf) != av.KAUSF {
+		t.Errorf("unexpected KAUSF: %s", av.KAUSF)
+	}
+
+	return milenage.SQNToUint64(sqn)
+}
+
+func TestGenerateAuthData(t *testing.T) {
+	srv := newServer(t, 0x10)
+
+	for i := uint64(1); i <= 2; i++ {
+		res := &udm.AuthenticationInfoResult{}
+		if status := post(t, srv, supi, &udm.AuthenticationInfoRequest{ServingNetworkName: snn}, res); status != http.StatusOK {
+			t.Fatalf("unexpected status: %d", status)
+		}
+		if res.AuthType != udm.AuthType5GAKA || res.SUPI != supi || res.AuthenticationVector.AvType != udm.AvType5GHEAKA {
+			t.Errorf("unexpected result: %+v", res)
+		}
+
+		if sqn := verifyVector(t, res.AuthenticationVector); sqn != 0x10+i {
+			t.Errorf("unexpected SQN: %x", sqn)
+		}
+	}
+}
+
+func TestGenerateAuthDataResync(t *testing.T) {
+	srv := newServer(t, 0x10)
+
+	// UE has SQNMS=0x1000 and rejects the AUTN received.
+	p := milenage.Params{K: [16]byte(k), OPc: [16]byte(opc), SQN: milenage.SQNFromUint64(0x1000)}
+	copy(p.RAND[:], []byte{0x23, 0x55, 0x3c, 0xbe, 0x96, 0x37, 0xa8, 0x9d, 0x21, 0x8a, 0xe6, 0x4d, 0xae, 0x47, 0xbf, 0x35})
+	auts := milenage.ComputeAUTS(p)
+
+	req := &udm.AuthenticationInfoRequest{
+		ServingNetworkName: snn,
+		ResynchronizationInfo: &udm.ResynchronizationInfo{
+			RAND: hex.EncodeToString(p.RAND[:]),
+			AUTS: hex.EncodeToString(auts[:]),
+		},
+	}
+	res := &udm.AuthenticationInfoResult{}
+	if status := post(t, srv, supi, req, res); status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", status)
+	}
+	if sqn := verifyVector(t, res.AuthenticationVector); sqn != 0x1001 {
+		t.Errorf("unexpected SQN: %x", sqn)
+	}
+
+	auts[13] ^= 1
+	req.ResynchronizationInfo.AUTS = hex.EncodeToString(auts[:])
+	pd := &udm.ProblemDetails{}
+	if status := post(t, srv, supi, req, pd); status != http.StatusForbidden || pd.Cause != "AUTHENTICATION_REJECTED" {
+		t.Errorf("unexpected response: %d, %+v", status, pd)
+	}
+}
+
+func TestGenerateAuthDataError(t *testing.T) {
+	srv := newServer(t, 0)
+
+	for _, c := range []struct {
+		description string
+		id          string
+		snn         string
+		status      int
+		cause       string
+	}{
+		{"unknown subscriber", "imsi-001010000000002", snn, http.StatusNotFound, "USER_NOT_FOUND"},
+		{"empty SNN", supi, "", http.StatusBadRequest, "MANDATORY_IE_INCORRECT"},
+		{"unsupported UE identity", "nai-user@example.com", snn, http.StatusNotImplemented, "UNSUPPORTED_UE_IDENTITY"},
+		{"invalid SUCI", "suci-0-001-01-0000-0-0-xyz", snn, http.StatusBadRequest, "MANDATORY_IE_INCORRECT"},
+		{"unknown key ID", "suci-0-001-01-0000-1-5-" + strings.Repeat("00", 45), snn, http.StatusNotImplemented, "UNSUPPORTED_PROTECTION_SCHEME"},
+	} {
+		pd := &udm.ProblemDetails{}
+		status := post(t, srv, c.id, &udm.AuthenticationInfoRequest{ServingNetworkName: c.snn}, pd)
+		if status != c.status || pd.Status != c.status || pd.Cause != c.cause {
+			t.Errorf("%s: unexpected response: %d, %+v", c.description, status, pd)
+		}
+	}
+}
+
+func TestGenerateAuthDataEAPAKAPrime(t *testing.T) {
+	store := auc.NewMemoryStore(&auc.Credential{SUPI: supi, K: k, OPc: opc, AMF: 0x8000, SQN: 0x10})
+	h := udm.NewHandler(store)
+	h.AuthType = udm.AuthTypeEAPAKAPrime
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	res := &udm.AuthenticationInfoResult{}
+	if status := post(t, srv, supi, &udm.AuthenticationInfoRequest{ServingNetworkName: snn}, res); status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", status)
+	}
+	av := res.AuthenticationVector
+	if res.AuthType != udm.AuthTypeEAPAKAPrime || av.AvType != udm.AvTypeEAPAKAPrime || av.XRESStar != "" || av.KAUSF != "" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	rnd, err := hex.DecodeString(av.RAND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := milenage.NewWithOPc(k, opc, rnd, 0x11, 0x8000)
+	if _, _, _, _, err := m.F2345(); err != nil {
+		t.Fatal(err)
+	}
+	ckPrime, ikPrime, err := m.ComputeCKIKPrime(snn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if av.XRES != hex.EncodeToString(m.RES) || av.CKPrime != hex.EncodeToString(ckPrime) || av.IKPrime != hex.EncodeToString(ikPrime) {
+		t.Errorf("unexpected vector: %+v", av)
+	}
+}
+
+func TestGenerateAuthDataSUCI(t *testing.T) {
+	hnKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := auc.NewMemoryStore(&auc.Credential{SUPI: supi, K: k, OPc: opc, AMF: 0x8000, SQN: 0x10})
+	h := udm.NewHandler(store)
+	h.HomeNetworkKeys = map[uint8]*ecdh.PrivateKey{1: hnKey}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	for _, pub := range []*ecdh.PublicKey{nil, hnKey.PublicKey()} {
+		s, err := suci.Conceal(supi, 2, "0", 1, pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := &udm.AuthenticationInfoResult{}
+		if status := post(t, srv, s.String(), &udm.AuthenticationInfoRequest{ServingNetworkName: snn}, res); status != http.StatusOK {
+			t.Fatalf("unexpected status: %d", status)
+		}
+		if res.SUPI != supi {
+			t.Errorf("unexpected SUPI: %s", res.SUPI)
+		}
+		verifyVector(t, res.AuthenticationVector)
+
+		if pub != nil {
+			s.SchemeOutput[len(s.SchemeOutput)-1] ^= 1
+			pd := &udm.ProblemDetails{}
+			if status := post(t, srv, s.String(), &udm.AuthenticationInfoRequest{ServingNetworkName: snn}, pd); status != http.StatusForbidden || pd.Cause != "AUTHENTICATION_REJECTED" {
+				t.Errorf("unexpected response: %d, %+v", status, pd)
+			}
+		}
+	}
+}
+
+func TestGenerateAuthDataWithSNN(t *testing.T) {
+	srv := newServer(t, 0x10)
+
+	// SNN which is not built from PLMN is used as it is.
+	for i, name := range []string{snn + ":00000000001", milenage.ServingNetworkNameNSWO} {
+		res := &udm.AuthenticationInfoResult{}
+		if status := post(t, srv, supi, &udm.AuthenticationInfoRequest{ServingNetworkName: name}, res); status != http.StatusOK {
+			t.Fatalf("%s: unexpected status: %d", name, status)
+		}
+		av := res.AuthenticationVector
+
+		rnd, err := hex.DecodeString(av.RAND)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := milenage.NewWithOPc(k, opc, rnd, uint64(0x11+i), 0x8000)
+		if _, _, _, _, err := m.F2345(); err != nil {
+			t.Fatal(err)
+		}
+		xresStar, err := hex.DecodeString(av.XRESStar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := m.VerifyRESStarWithSNN(xresStar, name); err != nil || !ok {
+			t.Errorf("%s: RES* verification failed: %v", name, err)
+		}
+		kausf, err := m.ComputeKAUSFWithSNN(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(kausf) != av.KAUSF {
+			t.Errorf("%s: unexpected KAUSF: %s", name, av.KAUSF)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage
+
+import (
+	"crypto/subtle"
+	"fmt"
+)
+
+// VerifyMACA recomputes MAC-A in the same way as F1 and reports whether it matches macA.
+// The comparison is done in constant time, and MACA in m is not updated.
+func (m *Milenage) VerifyMACA(macA []byte) (bool, error) {
+	mac, err := m.f1base(m.SQN, m.AMF)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(mac[:8], macA) == 1, nil
+}
+
+// VerifyMACS recomputes MAC-S in the same way as F1Star using sqn and amf given, and
+// reports whether it matches macS. The comparison is done in constant time, and MACS
+// in m is not updated.
+//
+// To verify MAC-S in AUTS, sqn should be SQNMS retrieved from AUTS and amf should
+// be zero (6.3.3, TS 33.102).
+func (m *Milenage) VerifyMACS(sqn, amf, macS []byte) (bool, error) {
+	if len(sqn) != 6 {
+		return false, fmt.Errorf("length of SQN should be %d, got: %d", 6, len(sqn))
+	}
+	if len(amf) != 2 {
+		return false, fmt.Errorf("length of AMF should be %d, got: %d", 2, len(amf))
+	}
+	if len(macS) != 8 {
+		return false, fmt.Errorf("length of MAC-S should be %d, got: %d", 8, len(macS))
+	}
+
+	mac, err := m.f1base(sqn, amf)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(mac[8:], macS) == 1, nil
+}
+
+// VerifyRES recomputes XRES in the same way as F2345 and reports whether it matches res.
+// The comparison is done in constant time, and the values in m are not updated.
+func (m *Milenage) VerifyRES(res []byte) (bool, error) {
+	xres, _, _, _, err := m.f2345()
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(xres, res) == 1, nil
+}
+
+// VerifyRESStar recomputes XRES* in the same way as F2345 and ComputeRESStar using mcc
+// and mnc given, and reports whether it matches resStar. The comparison is done in constant
+// time, and the values in m are not updated.
+func (m *Milenage) VerifyRESStar(resStar []byte, mcc, mnc string) (bool, error) {
+	p, err := NewPLMN(mcc, mnc)
+	if err != nil {
+		return false, err
+	}
+	return m.VerifyRESStarWithSNN(resStar, p.ServingNetworkName())
+}
+
+// VerifyRESStarWithSNN is the same as VerifyRESStar, but with the serving network name
+// given as it is, which is computed with ComputeRESStarWithSNN.
+func (m *Milenage) VerifyRESStarWithSNN(resStar []byte, snn string) (bool, error) {
+	if err := ValidateServingNetworkName(snn); err != nil {
+		return false, err
+	}
+
+	res, ck, ik, _, err := m.f2345()
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(m.resStar(ck, ik, res, snn), resStar) == 1, nil
+}
+
+// VerifyAUTS verifies MAC-S in AUTS received in the re-synchronisation procedure, and
+// returns SQNMS retrieved from AUTS, as described in 6.3.5, TS 33.102.
+// AK-S is computed with F5Star and MAC-S is computed with F1Star using AMF=0x0000, with
+// the RAND in m (which should be the one sent to the UE together with the AUTN rejected).
+// AKS and MACS in m are not updated.
+//
+// The returned SQNMS is meaningful only when the returned bool is true.
+func (m *Milenage) VerifyAUTS(auts []byte) ([]byte, bool, error) {
+	if len(auts) != 14 {
+		return nil, false, fmt.Errorf("length of AUTS should be %d, got: %d", 14, len(auts))
+	}
+
+	aks, err := m.f5Star()
+	if err != nil {
+		return nil, false, err
+	}
+
+	sqnMS := xor(auts[:6], aks)
+	ok, err := m.VerifyMACS(sqnMS, []byte{0x00, 0x00}, auts[6:])
+	if err != nil {
+		return nil, false, err
+	}
+	return sqnMS, ok, nil
+}
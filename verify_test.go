@@ -0,0 +1,136 @@
+// Copyright 2018-2023 milenage authors. All rights reserved.
+// Use of this source code is governed by a MIT-style license that can be
+// found in the LICENSE file.
+
+package milenage_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/wmnsk/milenage"
+)
+
+// flip returns a copy of b with the last bit flipped.
+func flip(b []byte) []byte {
+	c := bytes.Clone(b)
+	c[len(c)-1] ^= 1
+	return c
+}
+
+func TestVerify(t *testing.T) {
+	for _, c := range cases {
+		e := c.expected.mil
+		m := milenage.NewWithOPc(e.K, e.OPc, e.RAND, 0, 0)
+		m.SQN, m.AMF = e.SQN, e.AMF
+
+		verifiers := []struct {
+			name   string
+			verify func(b []byte) (bool, error)
+			value  []byte
+			// lengthErr is true if the value of wrong length is an error rather than a mismatch.
+			lengthErr bool
+		}{
+			{"MACA", m.VerifyMACA, e.MACA, false},
+			{"MACS", func(b []byte) (bool, error) { return m.VerifyMACS(e.SQN, e.AMF, b) }, e.MACS, true},
+			{"RES", m.VerifyRES, e.RES, false},
+			{"RESStar", func(b []byte) (bool, error) { return m.VerifyRESStar(b, "001", "01") }, e.RESStar, false},
+			{"RESStarWithSNN", func(b []byte) (bool, error) {
+				return m.VerifyRESStarWithSNN(b, "5G:mnc001.mcc001.3gppnetwork.org")
+			}, e.RESStar, false},
+		}
+
+		for _, v := range verifiers {
+			for _, tc := range []struct {
+				value []byte
+				want  bool
+			}{
+				{v.value, true},
+				{flip(v.value), false},
+				{v.value[:len(v.value)-1], false},
+				{nil, false},
+			} {
+				ok, err := v.verify(tc.value)
+				if v.lengthErr && len(tc.value) != len(v.value) {
+					if err == nil {
+						t.Errorf("%s/%s: expected error with %x", c.description, v.name, tc.value)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != tc.want {
+					t.Errorf("%s/%s: got %v with %x, want %v", c.description, v.name, ok, tc.value, tc.want)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyUnchanged(t *testing.T) {
+	c := cases[0]
+	e := c.expected.mil
+	m := milenage.NewWithOPc(e.K, e.OPc, e.RAND, 0, 0)
+	m.SQN, m.AMF = e.SQN, e.AMF
+	want := *m
+
+	for _, verify := range []func() (bool, error){
+		func() (bool, error) { return m.VerifyMACA(e.MACA) },
+		func() (bool, error) { return m.VerifyMACS(e.SQN, e.AMF, e.MACS) },
+		func() (bool, error) { return m.VerifyRES(e.RES) },
+		func() (bool, error) { return m.VerifyRESStar(e.RESStar, "001", "01") },
+		func() (bool, error) { return m.VerifyRESStarWithSNN(e.RESStar, "5G:mnc001.mcc001.3gppnetwork.org") },
+		func() (bool, error) { _, ok, err := m.VerifyAUTS(c.expected.auts); return ok, err },
+	} {
+		if ok, err := verify(); err != nil || !ok {
+			t.Fatalf("%s: verification failed: %v", c.description, err)
+		}
+	}
+
+	if diff := cmp.Diff(m, &want, cmpopts.IgnoreUnexported(milenage.Milenage{})); diff != "" {
+		t.Errorf("%s failed: \n%s", c.description, diff)
+	}
+}
+
+func TestVerifyMACSLength(t *testing.T) {
+	e := cases[0].expected.mil
+	m := milenage.NewWithOPc(e.K, e.OPc, e.RAND, 0, 0)
+
+	for _, c := range []struct {
+		description    string
+		sqn, amf, macS []byte
+	}{
+		{"short SQN", e.SQN[:5], e.AMF, e.MACS},
+		{"short AMF", e.SQN, e.AMF[:1], e.MACS},
+		{"nil AMF", e.SQN, nil, e.MACS},
+	} {
+		if _, err := m.VerifyMACS(c.sqn, c.amf, c.macS); err == nil {
+			t.Errorf("%s: expected error", c.description)
+		}
+	}
+}
+
+func TestVerifyAUTS(t *testing.T) {
+	for _, c := range cases {
+		e := c.expected.mil
+		m := milenage.NewWithOPc(e.K, e.OPc, e.RAND, 0, 0)
+
+		sqnMS, ok, err := m.VerifyAUTS(c.expected.auts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("%s: AUTS is expected to be valid", c.description)
+		}
+		if diff := cmp.Diff(sqnMS, e.SQN); diff != "" {
+			t.Errorf("%s failed: \n%s", c.description, diff)
+		}
+
+		if _, ok, err := m.VerifyAUTS(flip(c.expected.auts)); err != nil || ok {
+			t.Errorf("%s: tampered AUTS is expected to be invalid: %v", c.description, err)
+		}
+	}
+}